@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/engine"
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+const epdUsage = "usage: spencer epd <file> [--movetime ms] [--depth n] [--nodes n]"
+
+// epdResult is the outcome of the search of a single EPD position.
+type epdResult struct {
+	id       string
+	move     string // the move played, in SAN
+	expected string
+	solved   bool
+	// solvedAt is the time of the first iteration after which the engine
+	// kept a correct move, valid if solved is set
+	solvedAt time.Duration
+	// points and maxPoints are the STS scores from the c0 operation
+	points    int
+	maxPoints int
+}
+
+// runEPD() runs the "epd" subcommand: searches every position of an EPD test
+// suite and reports how many of them the engine solved.
+func runEPD(e uci.Engine, args []string) error {
+	fs := flag.NewFlagSet("epd", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), epdUsage)
+		fs.PrintDefaults()
+	}
+	movetime := fs.Int("movetime", 0, "search time per position, in milliseconds")
+	depth := fs.Int("depth", 0, "search depth per position")
+	nodes := fs.Int("nodes", 0, "search nodes per position")
+
+	// The flags can come before or after the file
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New(epdUsage)
+	}
+	file := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(epdUsage)
+	}
+
+	esl := uci.EngineSearchLimits{MoveTime: *movetime, Depth: *depth, Nodes: *nodes}
+	if esl.MoveTime == 0 && esl.Depth == 0 && esl.Nodes == 0 {
+		esl.MoveTime = 1000
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var results []epdResult
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		epd, err := engine.ParseEPD(line)
+		if err != nil {
+			return fmt.Errorf("%v:%v: %w", file, n, err)
+		}
+		r, err := searchEPD(e, epd, esl)
+		if err != nil {
+			return fmt.Errorf("%v:%v: %w", file, n, err)
+		}
+		if r.id == "" {
+			r.id = "line " + strconv.Itoa(n)
+		}
+		results = append(results, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	printEPDResults(os.Stdout, results)
+	return nil
+}
+
+// searchEPD() searches the position of epd with the limits esl and checks
+// the move played against the bm and am operations.
+func searchEPD(e uci.Engine, epd *engine.EPD, esl uci.EngineSearchLimits) (epdResult, error) {
+	pos := epd.Position
+	r := epdResult{id: epd.ID}
+
+	points, err := parseSTSPoints(pos, epd.Comments[0])
+	if err != nil {
+		return r, err
+	}
+	for _, p := range points {
+		if p > r.maxPoints {
+			r.maxPoints = p
+		}
+	}
+
+	var expected []string
+	for _, m := range epd.BestMoves {
+		expected = append(expected, pos.MoveToSAN(m))
+	}
+	for _, m := range epd.AvoidMoves {
+		expected = append(expected, "!"+pos.MoveToSAN(m))
+	}
+	r.expected = strings.Join(expected, " ")
+
+	isCorrect := func(m engine.Move) bool {
+		if m == engine.MoveNone {
+			return false
+		}
+		for _, am := range epd.AvoidMoves {
+			if m == am {
+				return false
+			}
+		}
+		if len(epd.BestMoves) == 0 {
+			return len(epd.AvoidMoves) != 0 || points[m] == r.maxPoints && r.maxPoints != 0
+		}
+		for _, bm := range epd.BestMoves {
+			if m == bm {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := make(chan string)
+	e.NewGame(out)
+	if err := e.SetPosition(pos.Fen(), out); err != nil {
+		return r, err
+	}
+
+	e.Search(esl, out)
+
+	// Follow the first move of the pv of every iteration to find the time
+	// after which the engine found the solution and did not change its mind.
+	var bestmove string
+	var found bool
+	for bestmove == "" {
+		line := <-out
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "bestmove":
+			bestmove = "(none)"
+			if len(fields) > 1 {
+				bestmove = fields[1]
+			}
+		case "info":
+			var t time.Duration
+			var pv string
+			for i := 1; i < len(fields)-1; i++ {
+				switch fields[i] {
+				case "time":
+					ms, _ := strconv.Atoi(fields[i+1])
+					t = time.Duration(ms) * time.Millisecond
+				case "pv":
+					pv = fields[i+1]
+				}
+			}
+			if pv == "" {
+				continue
+			}
+			correct := isCorrect(pos.NewUCIMove(pv))
+			if correct && !found {
+				r.solvedAt = t
+			}
+			found = correct
+		}
+	}
+
+	m := pos.NewUCIMove(bestmove)
+	if m == engine.MoveNone {
+		r.move = bestmove
+	} else {
+		r.move = pos.MoveToSAN(m)
+	}
+	r.solved = isCorrect(m)
+	if !found {
+		r.solvedAt = 0
+	}
+	r.points = points[m]
+
+	return r, nil
+}
+
+// parseSTSPoints() parses the c0 comment of the Strategic Test Suite, which
+// lists the moves worth points: "Nf3=10, Nd2=5, e4=3".
+func parseSTSPoints(pos *engine.Position, c0 string) (map[engine.Move]int, error) {
+	points := make(map[engine.Move]int)
+	for _, s := range strings.Split(c0, ",") {
+		s = strings.TrimSpace(s)
+		i := strings.IndexByte(s, '=')
+		if i == -1 {
+			continue
+		}
+		// Skip the comments that are not points, e.g. "exd8=Q"
+		p, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			continue
+		}
+		m, err := pos.ParseSAN(s[:i])
+		if err != nil {
+			return nil, fmt.Errorf("c0: %w", err)
+		}
+		points[m] = p
+	}
+	return points, nil
+}
+
+func printEPDResults(w io.Writer, results []epdResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tRESULT\tMOVE\tEXPECTED\tTIME\tPOINTS")
+
+	var solved, points, maxPoints int
+	var solvedTime time.Duration
+	for _, r := range results {
+		result, t := "fail", "-"
+		if r.solved {
+			result, t = "ok", r.solvedAt.String()
+			solved++
+			solvedTime += r.solvedAt
+		}
+		pts := "-"
+		if r.maxPoints != 0 {
+			pts = fmt.Sprintf("%v/%v", r.points, r.maxPoints)
+			points += r.points
+			maxPoints += r.maxPoints
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n", r.id, result, r.move, r.expected, t, pts)
+	}
+	tw.Flush()
+
+	if len(results) == 0 {
+		fmt.Fprintln(w, "\nNo positions")
+		return
+	}
+	fmt.Fprintf(w, "\nSolved: %v/%v (%.1f%%)\n", solved, len(results), float64(solved)*100/float64(len(results)))
+	fmt.Fprintf(w, "Unsolved: %v\n", len(results)-solved)
+	if solved != 0 {
+		fmt.Fprintf(w, "Average time to solution: %v\n", solvedTime/time.Duration(solved))
+	}
+	if maxPoints != 0 {
+		fmt.Fprintf(w, "Score: %v/%v (%.1f%%)\n", points, maxPoints, float64(points)*100/float64(maxPoints))
+	}
+}
@@ -9,12 +9,33 @@ import (
 )
 
 func main() {
-	e := engine.Engine{}
+	e := engine.NewEngine()
 	ei := uci.EngineInfo{
 		Name:    "Spencer",
 		Version: "developing",
 		Authros: []string{"Michalis Fotiadis"},
-		Options: []uci.EngineOption{},
+		Options: []uci.EngineOption{
+			uci.NewSpinOption("Hash", engine.DefaultHashSize, 1, engine.MaxHashSize, e.SetHashSize),
+			uci.NewButtonOption("Clear Hash", e.ClearHash),
+			uci.NewSpinOption("Threads", 1, 1, engine.MaxThreads, e.SetThreads),
+			uci.NewCheckOption("Ponder", false, e.SetPonder),
+			uci.NewSpinOption("Move Overhead", engine.DefaultMoveOverhead, 0, engine.MaxMoveOverhead, e.SetMoveOverhead),
+			uci.NewCheckOption("Null Move Pruning", true, func(b bool) { e.Selectivity().NullMove = b }),
+			uci.NewCheckOption("Late Move Reductions", true, func(b bool) { e.Selectivity().LMR = b }),
+			uci.NewCheckOption("Reverse Futility Pruning", true, func(b bool) { e.Selectivity().ReverseFutility = b }),
+			uci.NewCheckOption("Futility Pruning", true, func(b bool) { e.Selectivity().Futility = b }),
+			uci.NewCheckOption("Razoring", true, func(b bool) { e.Selectivity().Razoring = b }),
+			uci.NewCheckOption("Late Move Pruning", true, func(b bool) { e.Selectivity().LateMovePruning = b }),
+			uci.NewCheckOption("SEE Pruning", true, func(b bool) { e.Selectivity().SEEPruning = b }),
+		},
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "epd" {
+		if err := runEPD(e, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
 	if err := uci.Start(os.Stdin, os.Stdout, e, ei); err != nil {
@@ -47,8 +47,19 @@ func (b Bitboard) MoreThanOne() bool {
 	return b&(b-1) != 0
 }
 
-func (b Bitboard) lsb() int {
-	return bits.LeadingZeros64(uint64(b))
+func (b Bitboard) lsb() Square {
+	return Square(bits.TrailingZeros64(uint64(b)))
+}
+
+// popLSB() removes the least significant bit from b and returns its square.
+func (b *Bitboard) popLSB() Square {
+	s := b.lsb()
+	*b &= *b - 1
+	return s
+}
+
+func (b Bitboard) PopCount() int {
+	return bits.OnesCount64(uint64(b))
 }
 
 func (b Bitboard) Shift(d Direction) Bitboard {
@@ -97,7 +108,228 @@ func (m Magic) Inxex(occupied Bitboard) uint {
 	return uint(((occupied & m.Mask) * m.Magic) >> m.Shift)
 }
 
+// Pext() extracts the bits of b selected by mask and packs them into the low
+// bits of the result, the same as the BMI2 PEXT instruction. It is used
+// instead of Inxex() when building with the pext tag.
+func Pext(b, mask Bitboard) uint {
+	var res uint
+	for bit := uint(1); mask != 0; bit <<= 1 {
+		if b&mask&-mask != 0 {
+			res |= bit
+		}
+		mask &= mask - 1
+	}
+	return res
+}
+
 var (
 	RookMagics   [SquareNB]Magic
 	BishopMagics [SquareNB]Magic
+
+	rookTable   [0x19000]Bitboard
+	bishopTable [0x1480]Bitboard
 )
+
+// AttacksBB() returns the squares attacked by a piece of type pt (not a pawn)
+// placed on s, given the occupied squares.
+func AttacksBB(pt PieceType, s Square, occupied Bitboard) Bitboard {
+	switch pt {
+	case Bishop:
+		return BishopMagics[s].Attacks[BishopMagics[s].index(occupied)]
+	case Rook:
+		return RookMagics[s].Attacks[RookMagics[s].index(occupied)]
+	case Queen:
+		return AttacksBB(Bishop, s, occupied) | AttacksBB(Rook, s, occupied)
+	default:
+		return PseudoAttacks[pt][s]
+	}
+}
+
+// Aligned() returns true if the squares s1, s2 and s3 are aligned either on a
+// straight or on a diagonal line.
+func Aligned(s1, s2, s3 Square) bool {
+	return LineBB[s1][s2]&s3.Bitboard() != 0
+}
+
+func init() {
+	for i := range PopCount16 {
+		PopCount16[i] = uint8(bits.OnesCount16(uint16(i)))
+	}
+
+	for s := SquareA1; s <= SquareH8; s++ {
+		SquareBB[s] = 1 << s
+	}
+
+	for s1 := SquareA1; s1 <= SquareH8; s1++ {
+		for s2 := SquareA1; s2 <= SquareH8; s2++ {
+			fd := distance(int(s1.File()), int(s2.File()))
+			rd := distance(int(s1.Rank()), int(s2.Rank()))
+			if fd > rd {
+				SquareDistance[s1][s2] = uint8(fd)
+			} else {
+				SquareDistance[s1][s2] = uint8(rd)
+			}
+		}
+	}
+
+	initMagics(Rook, rookTable[:], &RookMagics, HasPext)
+	initMagics(Bishop, bishopTable[:], &BishopMagics, HasPext)
+
+	for s1 := SquareA1; s1 <= SquareH8; s1++ {
+		PawnAttacks[White][s1] = PawnAttacksBB(White, s1.Bitboard())
+		PawnAttacks[Black][s1] = PawnAttacksBB(Black, s1.Bitboard())
+
+		for _, step := range []int{-9, -8, -7, -1, 1, 7, 8, 9} {
+			PseudoAttacks[King][s1] |= safeDestination(s1, step)
+		}
+
+		for _, step := range []int{-17, -15, -10, -6, 6, 10, 15, 17} {
+			PseudoAttacks[Knight][s1] |= safeDestination(s1, step)
+		}
+
+		PseudoAttacks[Bishop][s1] = AttacksBB(Bishop, s1, 0)
+		PseudoAttacks[Rook][s1] = AttacksBB(Rook, s1, 0)
+		PseudoAttacks[Queen][s1] = PseudoAttacks[Bishop][s1] | PseudoAttacks[Rook][s1]
+
+		for _, pt := range []PieceType{Bishop, Rook} {
+			for s2 := SquareA1; s2 <= SquareH8; s2++ {
+				if PseudoAttacks[pt][s1]&s2.Bitboard() != 0 {
+					LineBB[s1][s2] = (AttacksBB(pt, s1, 0) & AttacksBB(pt, s2, 0)) | s1.Bitboard() | s2.Bitboard()
+					BetweenBB[s1][s2] = AttacksBB(pt, s1, s2.Bitboard()) & AttacksBB(pt, s2, s1.Bitboard())
+				}
+			}
+		}
+
+		// BetweenBB always includes the destination square, which simplifies
+		// the generation of check evasions.
+		for s2 := SquareA1; s2 <= SquareH8; s2++ {
+			BetweenBB[s1][s2] |= s2.Bitboard()
+		}
+	}
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// safeDestination() returns the bitboard of s + step, or an empty bitboard if
+// the step wraps around the board.
+func safeDestination(s Square, step int) Bitboard {
+	to := s + Square(step)
+	if to.IsOK() && SquareDistance[s][to] <= 2 {
+		return to.Bitboard()
+	}
+	return 0
+}
+
+// slidingAttack() computes the attacks of a rook or a bishop by walking the
+// rays, it is only used to build the magic bitboard tables.
+func slidingAttack(pt PieceType, s Square, occupied Bitboard) Bitboard {
+	directions := [4]Direction{North, South, East, West}
+	if pt == Bishop {
+		directions = [4]Direction{NorthEast, SouthEast, SouthWest, NorthWest}
+	}
+
+	var attacks Bitboard
+	for _, d := range directions {
+		sq := s
+		for safeDestination(sq, int(d)) != 0 {
+			sq += Square(d)
+			attacks |= sq.Bitboard()
+			if occupied&sq.Bitboard() != 0 {
+				break
+			}
+		}
+	}
+
+	return attacks
+}
+
+// initMagics() computes all rook and bishop attacks at startup. Magic
+// bitboards are used to look up attacks of sliding pieces. As a reference
+// see https://www.chessprogramming.org/Magic_Bitboards. In particular, here
+// we use the so called "fancy" approach. The magic numbers are found by a
+// search seeded per rank, so the tables are always the same. With pext set,
+// as when building with the pext tag, the tables are indexed with Pext() and
+// no magic numbers are needed.
+func initMagics(pt PieceType, table []Bitboard, magics *[SquareNB]Magic, pext bool) {
+	seeds := [RankNB]uint64{728, 10316, 55013, 32803, 12281, 15100, 16645, 255}
+
+	var occupancy, reference [4096]Bitboard
+	var epoch [4096]int
+	cnt, size := 0, 0
+
+	for s := SquareA1; s <= SquareH8; s++ {
+		// Board edges are not considered in the relevant occupancies
+		edges := ((Rank1BB | Rank8BB) & ^s.RankBB()) | ((FileABB | FileHBB) & ^s.File().Bitboard())
+
+		// Given a square s, the mask is the bitboard of sliding attacks from
+		// s computed on an empty board. The index must be big enough to
+		// contain all the attacks for each possible subset of the mask and so
+		// is 2 power the number of 1s of the mask. Hence we deduce the size of
+		// the shift to apply to the 64 bits word to get the index.
+		m := &magics[s]
+		m.Mask = slidingAttack(pt, s, 0) & ^edges
+		m.Shift = uint(64 - m.Mask.PopCount())
+
+		// Set the offset for the attacks table of the square. We have
+		// individual table sizes for each square with "Fancy Magic Bitboards".
+		if s == SquareA1 {
+			m.Attacks = table
+		} else {
+			m.Attacks = magics[s-1].Attacks[size:]
+		}
+
+		// Use Carry-Rippler trick to enumerate all subsets of masks[s] and
+		// store the corresponding sliding attack bitboard in reference[].
+		var b Bitboard
+		size = 0
+		for {
+			occupancy[size] = b
+			reference[size] = slidingAttack(pt, s, b)
+			if pext {
+				m.Attacks[Pext(b, m.Mask)] = reference[size]
+			}
+			size++
+			b = (b - m.Mask) & m.Mask
+			if b == 0 {
+				break
+			}
+		}
+
+		if pext {
+			continue
+		}
+
+		rng := NewPRNG(seeds[s.Rank()])
+
+		// Find a magic for square s picking up an (almost) random number
+		// until we find the one that passes the verification test.
+		for i := 0; i < size; {
+			for m.Magic = 0; ((m.Magic * m.Mask) >> 56).PopCount() < 6; {
+				m.Magic = Bitboard(rng.SparseRand())
+			}
+
+			// A good magic must map every possible occupancy to an index that
+			// looks up the correct sliding attack in the attacks[s] database.
+			// Note that we build up the database for square s as a side
+			// effect of verifying the magic. Keep track of the attempt count
+			// and save it in epoch[], little speed-up trick to avoid resetting
+			// m.Attacks[] after every failed attempt.
+			cnt++
+			for i = 0; i < size; i++ {
+				idx := m.Inxex(occupancy[i])
+
+				if epoch[idx] < cnt {
+					epoch[idx] = cnt
+					m.Attacks[idx] = reference[i]
+				} else if m.Attacks[idx] != reference[i] {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import "testing"
+
+// subsets() returns every subset of mask, by the Carry-Rippler trick.
+func subsets(mask Bitboard) []Bitboard {
+	var res []Bitboard
+	for b := Bitboard(0); ; {
+		res = append(res, b)
+		b = (b - mask) & mask
+		if b == 0 {
+			return res
+		}
+	}
+}
+
+// TestAttacksBB checks the slider attack tables against slidingAttack(), for
+// every subset of the relevant occupancy of every square and for random
+// occupancies.
+func TestAttacksBB(t *testing.T) {
+	for _, magics := range []*[SquareNB]Magic{&BishopMagics, &RookMagics} {
+		pt := Rook
+		if magics == &BishopMagics {
+			pt = Bishop
+		}
+		for s := SquareA1; s <= SquareH8; s++ {
+			for _, occupied := range subsets(magics[s].Mask) {
+				if got, want := AttacksBB(pt, s, occupied), slidingAttack(pt, s, occupied); got != want {
+					t.Fatalf("AttacksBB(%v, %v, %x) = %x, want %x", pt, s, uint64(occupied), uint64(got), uint64(want))
+				}
+			}
+		}
+	}
+
+	r := NewPRNG(2022)
+	for i := 0; i < 1000; i++ {
+		// Sparse and dense occupancies
+		occupied := Bitboard(r.SparseRand())
+		if i%2 == 1 {
+			occupied = Bitboard(r.Rand64() | r.Rand64())
+		}
+
+		for s := SquareA1; s <= SquareH8; s++ {
+			for _, pt := range []PieceType{Bishop, Rook} {
+				if got, want := AttacksBB(pt, s, occupied), slidingAttack(pt, s, occupied); got != want {
+					t.Fatalf("AttacksBB(%v, %v, %x) = %x, want %x", pt, s, uint64(occupied), uint64(got), uint64(want))
+				}
+			}
+			if got, want := AttacksBB(Queen, s, occupied), slidingAttack(Bishop, s, occupied)|slidingAttack(Rook, s, occupied); got != want {
+				t.Fatalf("AttacksBB(%v, %v, %x) = %x, want %x", Queen, s, uint64(occupied), uint64(got), uint64(want))
+			}
+		}
+	}
+}
+
+// newMagics() builds the rook and bishop attack tables indexed with Pext()
+// if pext is set, or else with the magic multiplication.
+func newMagics(pext bool) (rooks, bishops *[SquareNB]Magic) {
+	rooks, bishops = new([SquareNB]Magic), new([SquareNB]Magic)
+	initMagics(Rook, make([]Bitboard, len(rookTable)), rooks, pext)
+	initMagics(Bishop, make([]Bitboard, len(bishopTable)), bishops, pext)
+	return rooks, bishops
+}
+
+// TestMagicIndex checks that the tables indexed with the magic
+// multiplication and with Pext() give the same attacks, whatever the build.
+func TestMagicIndex(t *testing.T) {
+	mulRooks, mulBishops := newMagics(false)
+	pextRooks, pextBishops := newMagics(true)
+
+	for _, tables := range [][2]*[SquareNB]Magic{{mulRooks, pextRooks}, {mulBishops, pextBishops}} {
+		mul, pext := tables[0], tables[1]
+		for s := SquareA1; s <= SquareH8; s++ {
+			if mul[s].Mask != pext[s].Mask {
+				t.Fatalf("square %v: mask %x with the multiplication, %x with Pext()", s, uint64(mul[s].Mask), uint64(pext[s].Mask))
+			}
+			for _, occupied := range subsets(mul[s].Mask) {
+				// The occupancy outside the mask is ignored
+				occupied |= ^mul[s].Mask & Bitboard(0x0123456789abcdef)
+				if got, want := pext[s].Attacks[Pext(occupied, pext[s].Mask)], mul[s].Attacks[mul[s].Inxex(occupied)]; got != want {
+					t.Fatalf("square %v, occupancy %x: %x with Pext(), %x with the multiplication", s, uint64(occupied), uint64(got), uint64(want))
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkAttacksBB(b *testing.B) {
+	r := NewPRNG(2022)
+	var occupancies [256]Bitboard
+	for i := range occupancies {
+		occupancies[i] = Bitboard(r.Rand64() & r.Rand64())
+	}
+
+	mulRooks, mulBishops := newMagics(false)
+	pextRooks, pextBishops := newMagics(true)
+
+	b.Run("Mul", func(b *testing.B) {
+		var res Bitboard
+		for i := 0; i < b.N; i++ {
+			occupied := occupancies[i%len(occupancies)]
+			s := Square(i % int(SquareNB))
+			res ^= mulBishops[s].Attacks[mulBishops[s].Inxex(occupied)] ^ mulRooks[s].Attacks[mulRooks[s].Inxex(occupied)]
+		}
+		benchmarkSink = res
+	})
+
+	// Pext() is done in software, Go has no intrinsic for the instruction
+	b.Run("Pext", func(b *testing.B) {
+		var res Bitboard
+		for i := 0; i < b.N; i++ {
+			occupied := occupancies[i%len(occupancies)]
+			s := Square(i % int(SquareNB))
+			res ^= pextBishops[s].Attacks[Pext(occupied, pextBishops[s].Mask)] ^ pextRooks[s].Attacks[Pext(occupied, pextRooks[s].Mask)]
+		}
+		benchmarkSink = res
+	})
+}
+
+var benchmarkSink Bitboard
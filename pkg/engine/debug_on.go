@@ -0,0 +1,7 @@
+//go:build debug
+
+package engine
+
+// debugChecks enables the expensive consistency checks of the position after
+// every move.
+const debugChecks = true
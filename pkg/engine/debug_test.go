@@ -0,0 +1,44 @@
+//go:build debug
+
+package engine
+
+import "testing"
+
+// TestDebugRandomGames plays random games to their end, with some null moves,
+// and validates the position after every move. DoMove() also validates it
+// with the debug tag, and panics on the first inconsistency.
+func TestDebugRandomGames(t *testing.T) {
+	r := NewPRNG(8)
+	for game := 0; game < 200; game++ {
+		fen := StartFEN
+		if game%4 == 3 {
+			fen = "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"
+		}
+		pos, err := NewPosition(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for ply := 0; ply < 400 && !pos.IsDraw(ply); ply++ {
+			var ml MoveList
+			pos.Generate(Legal, &ml)
+			if ml.Len() == 0 {
+				break
+			}
+
+			if pos.Checkers() == 0 && r.Rand64()%16 == 0 {
+				pos.DoNullMove()
+				if err := pos.validate(); err != nil {
+					t.Fatalf("game %d: %v after a null move", game, err)
+				}
+				pos.UndoNullMove()
+			}
+
+			m := ml.Move(int(r.Rand64() % uint64(ml.Len())))
+			pos.DoMove(m)
+			if err := pos.validate(); err != nil {
+				t.Fatalf("game %d: %v after %v", game, err, m.UCIString(pos.IsChess960()))
+			}
+		}
+	}
+}
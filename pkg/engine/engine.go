@@ -1,32 +1,299 @@
 package engine
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/FotiadisM/spencer/pkg/uci"
 )
 
 // Engine satisfies the interface uci.Engine
 type Engine struct {
-	position *Position
+	position    *Position
+	debug       bool
+	tt          *TranspositionTable
+	threads     *threadPool
+	selectivity Selectivity
+	// moveOverhead is the time in milliseconds kept for each move
+	moveOverhead int
+	// ponder is set if the GUI may let the engine ponder
+	ponder bool
+
+	// stop is set atomically to interrupt the search
+	stop int32
+
+	mu        sync.Mutex
+	searching bool
+	// stopCh is closed by Stop() to wake an infinite search that has already
+	// finished, done is closed when the search returns
+	stopCh       chan struct{}
+	stopSignaled bool
+	done         chan struct{}
+	// ponderhitCh is closed by PonderHit() to wake a ponder search that has
+	// already finished
+	ponderhitCh chan struct{}
+	tm          *timeManager
+	// stopWaiting is set if Stop() waits for the search to return its move
+	stopWaiting bool
+	bestMove    string
+	ponderMove  string
+}
+
+func NewEngine() *Engine {
+	return &Engine{tt: NewTranspositionTable(DefaultHashSize), threads: newThreadPool(1), selectivity: DefaultSelectivity(), moveOverhead: DefaultMoveOverhead}
+}
+
+// Selectivity() returns the pruning techniques used by the search, they can
+// be changed between searches.
+func (e *Engine) Selectivity() *Selectivity {
+	return &e.selectivity
+}
+
+// SetHashSize() resizes the transposition table to mb megabytes, it must not
+// be called during a search.
+func (e *Engine) SetHashSize(mb int) {
+	e.tt.Resize(mb)
+}
+
+// SetThreads() sets the number of search threads, it must not be called
+// during a search.
+func (e *Engine) SetThreads(n int) {
+	e.threads.resize(n)
 }
 
-func (e Engine) SetDebug(b bool, out chan string) {
+// SetMoveOverhead() sets the time in milliseconds reserved for each move to
+// cover the communication delays with the GUI.
+func (e *Engine) SetMoveOverhead(ms int) {
+	e.moveOverhead = ms
 }
 
-func (e Engine) NewGame(out chan string) {
+// SetPonder() tells whether the GUI may let the engine ponder, the time
+// management then expects to save time on the ponderhits.
+func (e *Engine) SetPonder(b bool) {
+	e.ponder = b
 }
 
-func (e Engine) SetPosition(fen string, out chan string) {
-	e.position = NewPosition(fen)
-	out <- e.position.String()
+// ClearHash() empties the transposition table, it must not be called during
+// a search.
+func (e *Engine) ClearHash() {
+	e.tt.Clear()
 }
 
-func (e Engine) ApplyMove(mv string, out chan string) {
-	out <- mv + "\n"
+func (e *Engine) SetDebug(b bool, out chan string) {
+	e.debug = b
 }
 
-func (e Engine) Search(esl uci.EngineSearchLimits, out chan string) {
+func (e *Engine) NewGame(out chan string) {
+	e.tt.Clear()
+	e.threads.clear()
 }
 
-func (e Engine) Stop() (bm string, po string) {
-	return "", ""
+func (e *Engine) SetPosition(fen string, out chan string) error {
+	pos, err := NewPosition(fen)
+	if err != nil {
+		return err
+	}
+	e.position = pos
+	if e.debug {
+		out <- e.position.String()
+	}
+	return nil
+}
+
+// currentPosition() returns the position set by the GUI, defaulting to the
+// starting position.
+func (e *Engine) currentPosition() *Position {
+	if e.position == nil {
+		e.position, _ = NewPosition(StartFEN)
+	}
+	return e.position
+}
+
+func (e *Engine) ApplyMove(mv string, out chan string) error {
+	pos := e.currentPosition()
+	m := pos.NewUCIMove(mv)
+	if m == MoveNone {
+		return fmt.Errorf("illegal move %v in position %v", mv, pos.Fen())
+	}
+	pos.DoMove(m)
+	if e.debug {
+		out <- pos.String()
+	}
+	return nil
+}
+
+// Search() starts the search of the current position and returns, the best
+// move is sent to out when the search is over, unless it is returned by a
+// concurrent call to Stop(). Without any limit, or with infinite, the search
+// only ends when Stop() is called. With ponder the search ignores the time
+// limits and only ends when Stop() or PonderHit() are called.
+func (e *Engine) Search(esl uci.EngineSearchLimits, out chan string) {
+	// The search is marked as started before returning, so that a following
+	// Stop() or PonderHit() is never lost
+	e.mu.Lock()
+	e.start()
+	pos := e.currentPosition().Copy()
+	e.tm = newTimeManager(esl, pos.SideToMove(), pos.GamePly(), time.Duration(e.moveOverhead)*time.Millisecond, e.ponder)
+	stopCh, ponderhitCh, done, tm := e.stopCh, e.ponderhitCh, e.done, e.tm
+	e.mu.Unlock()
+
+	go e.search(pos, esl, tm, stopCh, ponderhitCh, done, out)
+}
+
+// start() stops the previous search and waits for it to return, then marks a
+// new search as started. e.mu must be held.
+func (e *Engine) start() {
+	for e.searching {
+		e.signalStop()
+		done := e.done
+		e.mu.Unlock()
+		<-done
+		e.mu.Lock()
+	}
+	e.searching = true
+	e.stopCh = make(chan struct{})
+	e.stopSignaled = false
+	e.ponderhitCh = make(chan struct{})
+	e.done = make(chan struct{})
+	atomic.StoreInt32(&e.stop, 0)
+}
+
+// search() runs the search started by Search() and sends its best move.
+func (e *Engine) search(pos *Position, esl uci.EngineSearchLimits, tm *timeManager, stopCh, ponderhitCh, done chan struct{}, out chan string) {
+	e.tt.NewSearch()
+	infinite := esl.Infinite ||
+		(esl.WTime == 0 && esl.BTime == 0 && esl.MoveTime == 0 && esl.Depth == 0 && esl.Nodes == 0 && esl.Mate == 0)
+	bm, po := e.threads.search(pos, esl, tm, e.tt, e.selectivity, &e.stop, out, func() {
+		// The move is not sent before the GUI ends the ponder search
+		switch {
+		case infinite:
+			<-stopCh
+		case esl.Ponder:
+			select {
+			case <-stopCh:
+			case <-ponderhitCh:
+			}
+		}
+	})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.bestMove = "(none)"
+	e.ponderMove = ""
+	if bm != MoveNone {
+		e.bestMove = bm.UCIString(pos.IsChess960())
+	}
+	if po != MoveNone {
+		e.ponderMove = po.UCIString(pos.IsChess960())
+	}
+
+	if !e.stopWaiting {
+		if e.ponderMove == "" {
+			out <- fmt.Sprintf("bestmove %v\n", e.bestMove)
+		} else {
+			out <- fmt.Sprintf("bestmove %v ponder %v\n", e.bestMove, e.ponderMove)
+		}
+	}
+	e.searching = false
+	close(done)
+}
+
+// Stop() stops the search and waits for it to return the best move and the
+// ponder move. If there is no search running it returns empty strings.
+func (e *Engine) Stop() (bm string, po string) {
+	e.mu.Lock()
+	if !e.searching {
+		e.mu.Unlock()
+		return "", ""
+	}
+	if e.stopWaiting {
+		e.mu.Unlock()
+		return "", ""
+	}
+	e.stopWaiting = true
+	e.signalStop()
+	done := e.done
+	e.mu.Unlock()
+
+	<-done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopWaiting = false
+	return e.bestMove, e.ponderMove
+}
+
+// PonderHit() tells the engine that the opponent played the move it was
+// pondering on, the ponder search goes on as a normal search.
+func (e *Engine) PonderHit(out chan string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.searching {
+		return
+	}
+	e.signalPonderhit()
+}
+
+// signalPonderhit() starts the clock of the running search, stopping it if
+// it has already searched long enough. e.mu must be held.
+func (e *Engine) signalPonderhit() {
+	select {
+	case <-e.ponderhitCh:
+		return
+	default:
+	}
+	if e.tm.ponderhit() {
+		e.signalStop()
+	}
+	close(e.ponderhitCh)
+}
+
+// signalStop() interrupts the running search, e.mu must be held.
+func (e *Engine) signalStop() {
+	atomic.StoreInt32(&e.stop, 1)
+	if !e.stopSignaled {
+		close(e.stopCh)
+		e.stopSignaled = true
+	}
+}
+
+// Perft() counts the leaf nodes of the legal move tree of the current
+// position, reporting them by root move. It runs like a search: it is
+// interrupted by Stop() and a new search waits for it to return. It works on
+// a copy of the position so that the GUI can set a new one meanwhile.
+func (e *Engine) Perft(depth int, out chan string) {
+	e.mu.Lock()
+	e.start()
+	pos := e.currentPosition().Copy()
+	done := e.done
+	e.mu.Unlock()
+
+	go func() {
+		defer func() {
+			e.mu.Lock()
+			// There is no best move for Stop() to send
+			e.bestMove, e.ponderMove = "", ""
+			e.searching = false
+			close(done)
+			e.mu.Unlock()
+		}()
+
+		start := time.Now()
+		res := divide(pos, depth, &e.stop)
+		if atomic.LoadInt32(&e.stop) != 0 {
+			return
+		}
+
+		var nodes uint64
+		for _, mc := range res {
+			out <- fmt.Sprintf("%v: %v\n", mc.Move.UCIString(pos.IsChess960()), mc.Nodes)
+			nodes += mc.Nodes
+		}
+
+		elapsed := time.Since(start).Milliseconds()
+		out <- fmt.Sprintf("\nNodes searched: %v\nTime: %v ms\nNodes/second: %v\n", nodes, elapsed, nodes*1000/uint64(elapsed+1))
+	}()
 }
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+// runUCI() sends the commands to a new engine through the UCI loop, all at
+// once as a fast GUI would, and returns the moves of the first n bestmove
+// lines.
+func runUCI(t *testing.T, commands string, n int) []string {
+	t.Helper()
+
+	// The input is kept open until the searches are over, the UCI loop
+	// returns at its end
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go uci.Start(inR, outW, NewEngine(), uci.EngineInfo{})
+	go io.WriteString(inW, commands)
+	defer inW.Close()
+
+	moves := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 1 && fields[0] == "bestmove" {
+				moves <- fields[1]
+			}
+		}
+	}()
+
+	var res []string
+	for len(res) < n {
+		select {
+		case m := <-moves:
+			res = append(res, m)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("got %d bestmove, want %d", len(res), n)
+		}
+	}
+
+	// No other search may send a move
+	select {
+	case m := <-moves:
+		t.Fatalf("unexpected bestmove %v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	return res
+}
+
+func TestSearchStartStop(t *testing.T) {
+	// Every go is stopped by the following stop, even when they are sent
+	// before the search started
+	commands := strings.Repeat("position startpos\ngo infinite\nstop\n", 20) +
+		strings.Repeat("go infinite\nstop\nposition startpos moves e2e4\ngo infinite\nstop\n", 20) +
+		strings.Repeat("go ponder wtime 1000 btime 1000\nstop\n", 20)
+	runUCI(t, commands, 80)
+
+	// The search is of the position set before the go command
+	moves := runUCI(t, "position startpos moves e2e4\ngo depth 2\nposition startpos\ngo depth 2\n", 2)
+	pos, _ := NewPosition(StartFEN)
+	if pos.NewUCIMove(moves[1]) == MoveNone {
+		t.Errorf("bestmove %v is illegal in the starting position", moves[1])
+	}
+	pos.DoMove(pos.NewUCIMove("e2e4"))
+	if pos.NewUCIMove(moves[0]) == MoveNone {
+		t.Errorf("bestmove %v is illegal after e2e4", moves[0])
+	}
+}
+
+func TestSearchPonder(t *testing.T) {
+	// A ponderhit turns the ponder search into a timed search that ends by
+	// itself
+	runUCI(t, strings.Repeat("position startpos\ngo ponder wtime 200 btime 200\nponderhit\n", 10), 10)
+
+	// Without ponderhit the ponder search does not end before stop, even
+	// after the time allocated to the move
+	outR, outW := io.Pipe()
+	in, inW := io.Pipe()
+	go uci.Start(in, outW, NewEngine(), uci.EngineInfo{})
+	bestmove := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "bestmove") {
+				bestmove <- scanner.Text()
+			}
+		}
+	}()
+
+	io.WriteString(inW, "position startpos\ngo ponder wtime 100 btime 100 movetime 50\n")
+	select {
+	case line := <-bestmove:
+		t.Fatalf("the ponder search sent %q before stop", line)
+	case <-time.After(500 * time.Millisecond):
+	}
+	io.WriteString(inW, "stop\n")
+	select {
+	case line := <-bestmove:
+		if !strings.Contains(line, " ponder ") {
+			t.Errorf("%q: no ponder move", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ponder search did not stop")
+	}
+	inW.Close()
+}
+
+func TestApplyMove(t *testing.T) {
+	out := make(chan string, 1)
+	e := &Engine{}
+	if err := e.SetPosition("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", out); err != nil {
+		t.Fatal(err)
+	}
+	for _, mv := range []string{"e1g1", "e8c8"} {
+		if err := e.ApplyMove(mv, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An illegal move is an error and leaves the position unchanged
+	want := "2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2"
+	for _, mv := range []string{"e1g1", "g1g3", "f1f1", "e2e4", "a1"} {
+		if err := e.ApplyMove(mv, out); err == nil {
+			t.Errorf("ApplyMove(%q): expected an error", mv)
+		}
+	}
+	if got := e.position.Fen(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EPD is a position in Extended Position Description notation: the first
+// four fields of a FEN followed by operations. The common operations are
+// parsed in their own fields, with the moves resolved from SAN.
+type EPD struct {
+	Position *Position
+
+	BestMoves  []Move     // bm
+	AvoidMoves []Move     // am
+	ID         string     // id
+	Comments   [10]string // c0 to c9
+	ACD        int        // acd, analysis count depth
+	CE         int        // ce, centipawn evaluation
+	HasCE      bool
+	PM         Move   // pm, predicted move
+	PV         []Move // pv, predicted variation
+
+	// Operations holds the rest of the operations, in order
+	Operations []EPDOperation
+}
+
+type EPDOperation struct {
+	Opcode   string
+	Operands []string
+}
+
+// ParseEPD() parses a single EPD line. The halfmove clock and the fullmove
+// number are taken from the hmvc and fmvn operations if present.
+func ParseEPD(line string) (*EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid epd %q: expected at least 4 fields, got %d", line, len(fields))
+	}
+
+	// Skip the 4 fields of the position
+	rest := strings.TrimSpace(line)
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[strings.IndexAny(rest+" ", " \t"):]
+	}
+
+	ops, err := parseEPDOperations(rest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid epd %q: %w", line, err)
+	}
+
+	hmvc, fmvn := "0", "1"
+	for _, op := range ops {
+		switch op.Opcode {
+		case "hmvc":
+			hmvc = strings.Join(op.Operands, " ")
+		case "fmvn":
+			fmvn = strings.Join(op.Operands, " ")
+		}
+	}
+
+	pos, err := NewPosition(strings.Join(append(fields[:4:4], hmvc, fmvn), " "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid epd %q: %w", line, err)
+	}
+
+	e := &EPD{Position: pos}
+	for _, op := range ops {
+		if err := e.setOperation(op); err != nil {
+			return nil, fmt.Errorf("invalid epd %q: %s: %w", line, op.Opcode, err)
+		}
+	}
+
+	return e, nil
+}
+
+func (e *EPD) setOperation(op EPDOperation) error {
+	var err error
+
+	switch op.Opcode {
+	case "bm":
+		e.BestMoves, err = e.parseMoves(op.Operands, false)
+	case "am":
+		e.AvoidMoves, err = e.parseMoves(op.Operands, false)
+	case "pv":
+		e.PV, err = e.parseMoves(op.Operands, true)
+	case "pm":
+		if len(op.Operands) != 1 {
+			return fmt.Errorf("expected one move")
+		}
+		e.PM, err = e.Position.ParseSAN(op.Operands[0])
+	case "id":
+		e.ID = strings.Join(op.Operands, " ")
+	case "c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9":
+		e.Comments[op.Opcode[1]-'0'] = strings.Join(op.Operands, " ")
+	case "acd":
+		e.ACD, err = parseEPDInt(op.Operands)
+	case "ce":
+		e.CE, err = parseEPDInt(op.Operands)
+		e.HasCE = err == nil
+	case "hmvc", "fmvn":
+		// Part of the position
+	default:
+		e.Operations = append(e.Operations, op)
+	}
+
+	return err
+}
+
+// parseMoves() converts SAN operands to moves. The moves of a variation are
+// played in sequence, otherwise they are all alternatives from the position.
+func (e *EPD) parseMoves(operands []string, variation bool) ([]Move, error) {
+	moves := make([]Move, 0, len(operands))
+	defer func() {
+		if variation {
+			for i := len(moves) - 1; i >= 0; i-- {
+				e.Position.UndoMove(moves[i])
+			}
+		}
+	}()
+
+	for _, san := range operands {
+		m, err := e.Position.ParseSAN(san)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+		if variation {
+			e.Position.DoMove(m)
+		}
+	}
+
+	return moves, nil
+}
+
+func parseEPDInt(operands []string) (int, error) {
+	if len(operands) != 1 {
+		return 0, fmt.Errorf("expected one integer")
+	}
+	return strconv.Atoi(operands[0])
+}
+
+// parseEPDOperations() splits the operations of an EPD line. Every operation
+// is an opcode followed by its operands and terminated by a semicolon.
+// Operands can be strings in double quotes.
+func parseEPDOperations(str string) ([]EPDOperation, error) {
+	var ops []EPDOperation
+	var op *EPDOperation
+
+	for i := 0; i < len(str); {
+		switch c := str[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			if op == nil {
+				return nil, fmt.Errorf("empty operation")
+			}
+			ops = append(ops, *op)
+			op = nil
+			i++
+		case c == '"':
+			end := strings.IndexByte(str[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			if op == nil {
+				return nil, fmt.Errorf("string without an opcode")
+			}
+			op.Operands = append(op.Operands, str[i+1:i+1+end])
+			i += end + 2
+		default:
+			end := strings.IndexAny(str[i:], " \t;")
+			if end == -1 {
+				end = len(str) - i
+			}
+			if op == nil {
+				op = &EPDOperation{Opcode: str[i : i+end]}
+			} else {
+				op.Operands = append(op.Operands, str[i:i+end])
+			}
+			i += end
+		}
+	}
+
+	// Be lenient with a missing semicolon after the last operation
+	if op != nil {
+		ops = append(ops, *op)
+	}
+
+	return ops, nil
+}
+
+// String() returns the EPD line. The halfmove clock and fullmove number of
+// the position are written as hmvc and fmvn operations when they are not the
+// default ones.
+func (e *EPD) String() string {
+	fields := strings.Fields(e.Position.Fen())
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(fields[:4], " "))
+
+	writeOp := func(opcode string, operands ...string) {
+		sb.WriteString(" " + opcode)
+		for _, o := range operands {
+			sb.WriteString(" " + o)
+		}
+		sb.WriteString(";")
+	}
+
+	if len(e.BestMoves) != 0 {
+		writeOp("bm", e.sanMoves(e.BestMoves, false)...)
+	}
+	if len(e.AvoidMoves) != 0 {
+		writeOp("am", e.sanMoves(e.AvoidMoves, false)...)
+	}
+	if e.PM != MoveNone {
+		writeOp("pm", e.Position.MoveToSAN(e.PM))
+	}
+	if len(e.PV) != 0 {
+		writeOp("pv", e.sanMoves(e.PV, true)...)
+	}
+	if e.ID != "" {
+		writeOp("id", `"`+e.ID+`"`)
+	}
+	if e.ACD != 0 {
+		writeOp("acd", strconv.Itoa(e.ACD))
+	}
+	if e.HasCE {
+		writeOp("ce", strconv.Itoa(e.CE))
+	}
+	for i, c := range e.Comments {
+		if c != "" {
+			writeOp("c"+strconv.Itoa(i), `"`+c+`"`)
+		}
+	}
+	if fields[4] != "0" {
+		writeOp("hmvc", fields[4])
+	}
+	if fields[5] != "1" {
+		writeOp("fmvn", fields[5])
+	}
+	for _, op := range e.Operations {
+		operands := make([]string, len(op.Operands))
+		for i, o := range op.Operands {
+			operands[i] = o
+			if strings.ContainsAny(o, " \t;") || o == "" {
+				operands[i] = `"` + o + `"`
+			}
+		}
+		writeOp(op.Opcode, operands...)
+	}
+
+	return sb.String()
+}
+
+func (e *EPD) sanMoves(moves []Move, variation bool) []string {
+	san := make([]string, len(moves))
+	for i, m := range moves {
+		san[i] = e.Position.MoveToSAN(m)
+		if variation {
+			e.Position.DoMove(m)
+		}
+	}
+	if variation {
+		for i := len(moves) - 1; i >= 0; i-- {
+			e.Position.UndoMove(moves[i])
+		}
+	}
+	return san
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEPD(t *testing.T) {
+	line := `r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - bm Qxf7#; am Qxe5+ Nf3; ` +
+		`pm Qxf7#; pv Qxf7#; id "Scholar's mate; almost"; c0 "Qxf7#=10, Qf3=2"; c9 "last comment"; ` +
+		`acd 12; ce 32766; hmvc 4; fmvn 4; sv "a;b" Bc4 "";`
+	epd, err := ParseEPD(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := epd.Position.Fen(); got != "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4" {
+		t.Errorf("position: got %v", got)
+	}
+	qxf7 := epd.Position.NewUCIMove("h5f7")
+	if len(epd.BestMoves) != 1 || epd.BestMoves[0] != qxf7 {
+		t.Errorf("bm: got %v", epd.BestMoves)
+	}
+	if len(epd.AvoidMoves) != 2 || epd.AvoidMoves[0] != epd.Position.NewUCIMove("h5e5") || epd.AvoidMoves[1] != epd.Position.NewUCIMove("g1f3") {
+		t.Errorf("am: got %v", epd.AvoidMoves)
+	}
+	if epd.PM != qxf7 || len(epd.PV) != 1 || epd.PV[0] != qxf7 {
+		t.Errorf("pm: got %v, pv: got %v", epd.PM, epd.PV)
+	}
+	if epd.ID != "Scholar's mate; almost" {
+		t.Errorf("id: got %q", epd.ID)
+	}
+	if epd.Comments[0] != "Qxf7#=10, Qf3=2" || epd.Comments[9] != "last comment" || epd.Comments[1] != "" {
+		t.Errorf("comments: got %q", epd.Comments)
+	}
+	if epd.ACD != 12 || epd.CE != 32766 || !epd.HasCE {
+		t.Errorf("acd: got %v, ce: got %v %v", epd.ACD, epd.CE, epd.HasCE)
+	}
+	if len(epd.Operations) != 1 || epd.Operations[0].Opcode != "sv" ||
+		strings.Join(epd.Operations[0].Operands, "|") != "a;b|Bc4|" {
+		t.Errorf("operations: got %q", epd.Operations)
+	}
+
+	// The operations of the variation are played from the position, which
+	// is left unchanged
+	epd, err = ParseEPD("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - pv e4 e5 Nf3; ce -15;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := epd.Position.Fen(); got != StartFEN {
+		t.Errorf("position after pv: got %v", got)
+	}
+	if len(epd.PV) != 3 || epd.PV[2].String() != "g1f3" {
+		t.Errorf("pv: got %v", epd.PV)
+	}
+	if epd.CE != -15 || !epd.HasCE {
+		t.Errorf("ce: got %v", epd.CE)
+	}
+}
+
+func TestEPDRoundTrip(t *testing.T) {
+	tests := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4 d4; id \"start\";",
+		"1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id \"BK.01\";",
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - am Qxe5+; pm Qxf7#; pv Qxf7#; acd 3; ce 32766; c0 \"mate; in one\"; c5 \"x\";",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - pv e4 e5 Nf3 Nc6; ce 0;",
+		"4k3/8/8/8/8/8/8/4K3 w - - hmvc 12; fmvn 40; noop; sv \"a;b\" c \"\";",
+		"r3k2r/8/8/8/8/8/8/1R2K1R1 w GBha - bm O-O;",
+	}
+
+	for _, line := range tests {
+		epd, err := ParseEPD(line)
+		if err != nil {
+			t.Errorf("%v: %v", line, err)
+			continue
+		}
+		if got := epd.String(); got != line {
+			t.Errorf("got\n%v\nwant\n%v", got, line)
+		}
+	}
+}
+
+func TestParseEPDInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"empty", ""},
+		{"missing fields", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq"},
+		{"invalid position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN w KQkq -"},
+		{"illegal best move", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e5;"},
+		{"illegal avoid move", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - am Ke2;"},
+		{"illegal move in the variation", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - pv e4 e4;"},
+		{"two predicted moves", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - pm e4 d4;"},
+		{"invalid depth", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - acd x;"},
+		{"invalid evaluation", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - ce 1 2;"},
+		{"invalid halfmove clock", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - hmvc x;"},
+		{"unterminated string", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - id \"start;"},
+		{"empty operation", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4;;"},
+		{"string without an opcode", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - \"start\";"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseEPD(tt.line); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+// The history tables score quiet moves and captures by how often they caused
+// a beta cutoff. They are updated with a gravity formula that keeps every
+// entry within [-d, d] and makes the old values decay.
+
+// butterflyHistory is indexed by the side to move and the from and to squares
+// of a move.
+type butterflyHistory [ColorNB][SquareNB * SquareNB]int16
+
+// pieceToHistory is indexed by the moved piece and the destination square.
+type pieceToHistory [PieceNB][SquareNB]int16
+
+// continuationHistory scores a move by the move played some plies earlier,
+// it is indexed by the piece and the destination square of the earlier move.
+type continuationHistory [PieceNB][SquareNB]pieceToHistory
+
+// capturePieceToHistory is indexed by the moved piece, the destination
+// square and the captured piece type.
+type capturePieceToHistory [PieceNB][SquareNB][PieceTypeNB]int16
+
+const (
+	butterflyHistoryD = 7183
+	pieceToHistoryD   = 29952
+	captureHistoryD   = 10692
+	maxHistoryBonus   = 1200
+)
+
+// history holds all the move ordering statistics of a search thread.
+type history struct {
+	main         butterflyHistory
+	capture      capturePieceToHistory
+	continuation continuationHistory
+	counterMoves [PieceNB][SquareNB]Move
+}
+
+func newHistory() *history {
+	return &history{}
+}
+
+func (h *history) clear() {
+	*h = history{}
+}
+
+func updateHistory(entry *int16, bonus, d int) {
+	if bonus > d {
+		bonus = d
+	} else if bonus < -d {
+		bonus = -d
+	}
+	abs := bonus
+	if abs < 0 {
+		abs = -abs
+	}
+	*entry += int16(bonus - int(*entry)*abs/d)
+}
+
+// statBonus() returns the history bonus of a move causing a cutoff at depth.
+func statBonus(depth int) int {
+	if bonus := 16*depth*depth + 64*depth; bonus < maxHistoryBonus {
+		return bonus
+	}
+	return maxHistoryBonus
+}
+
+func fromTo(m Move) int {
+	return int(m) & 0xfff
+}
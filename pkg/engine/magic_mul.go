@@ -0,0 +1,11 @@
+//go:build !pext
+
+package engine
+
+// HasPext reports whether the slider attack tables are indexed with Pext()
+// instead of the magic multiplication.
+const HasPext = false
+
+func (m Magic) index(occupied Bitboard) uint {
+	return m.Inxex(occupied)
+}
@@ -0,0 +1,286 @@
+package engine
+
+type GenType int
+
+const (
+	Captures GenType = iota
+	Quiets
+	QuietChecks
+	Evasions
+	NonEvasions
+	Legal
+)
+
+// MaxMoves is the maximum number of legal moves in any reachable position.
+const MaxMoves = 256
+
+type ExtMove struct {
+	Move
+	Value int
+}
+
+// MoveList is a fixed size list of moves, so generating moves does not need
+// any allocation.
+type MoveList struct {
+	moves [MaxMoves]ExtMove
+	size  int
+}
+
+func (ml *MoveList) Len() int {
+	return ml.size
+}
+
+func (ml *MoveList) Move(i int) Move {
+	return ml.moves[i].Move
+}
+
+// Slice() returns the generated moves. It shares memory with ml, so the moves
+// can be scored and sorted in place.
+func (ml *MoveList) Slice() []ExtMove {
+	return ml.moves[:ml.size]
+}
+
+func (ml *MoveList) Reset() {
+	ml.size = 0
+}
+
+func (ml *MoveList) Contains(m Move) bool {
+	for i := 0; i < ml.size; i++ {
+		if ml.moves[i].Move == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (ml *MoveList) add(m Move) {
+	ml.moves[ml.size].Move = m
+	ml.size++
+}
+
+// Generate() appends to ml the moves of type gt:
+//
+// Captures     generates all pseudo-legal captures plus queen promotions
+// Quiets       generates all pseudo-legal non-captures and underpromotions
+// QuietChecks  generates all pseudo-legal non-captures giving check, except castling and promotions
+// Evasions     generates all pseudo-legal check evasions when the side to move is in check
+// NonEvasions  generates all pseudo-legal captures and non-captures
+// Legal        generates all the legal moves in the given position
+//
+// Captures, Quiets, QuietChecks and NonEvasions must not be used when the
+// side to move is in check.
+func (p *Position) Generate(gt GenType, ml *MoveList) {
+	if gt == Legal {
+		p.generateLegal(ml)
+		return
+	}
+
+	p.generateAll(gt, ml)
+}
+
+func (p *Position) generateLegal(ml *MoveList) {
+	us := p.sideToMove
+	pinned := p.KingBlockers(us) & p.PiecesByColor(us)
+	ksq := p.KingSquare(us)
+
+	cur := ml.size
+	if p.Checkers() != 0 {
+		p.generateAll(Evasions, ml)
+	} else {
+		p.generateAll(NonEvasions, ml)
+	}
+
+	for cur != ml.size {
+		m := ml.moves[cur].Move
+		if (pinned&m.FromSquare().Bitboard() != 0 || m.FromSquare() == ksq || m.Type() == EnPassant) && !p.IsMoveLegal(m) {
+			ml.size--
+			ml.moves[cur] = ml.moves[ml.size]
+		} else {
+			cur++
+		}
+	}
+}
+
+func (p *Position) generateAll(gt GenType, ml *MoveList) {
+	us := p.sideToMove
+	checks := gt == QuietChecks
+	ksq := p.KingSquare(us)
+
+	var target Bitboard
+	switch gt {
+	case Evasions:
+		target = BetweenBB[ksq][p.Checkers().lsb()]
+	case NonEvasions:
+		target = ^p.PiecesByColor(us)
+	case Captures:
+		target = p.PiecesByColor(1 - us)
+	default: // Quiets and QuietChecks
+		target = ^p.PiecesByType(AllPieces)
+	}
+
+	// Skip generating non-king moves when in double check
+	if gt != Evasions || !p.Checkers().MoreThanOne() {
+		p.generatePawnMoves(gt, ml, target)
+		for pt := Knight; pt <= Queen; pt++ {
+			p.generateMoves(pt, checks, ml, target)
+		}
+	}
+
+	if !checks || p.KingBlockers(1-us)&ksq.Bitboard() != 0 {
+		b := AttacksBB(King, ksq, 0)
+		if gt == Evasions {
+			b &= ^p.PiecesByColor(us)
+		} else {
+			b &= target
+		}
+		if checks {
+			b &= ^AttacksBB(Queen, p.KingSquare(1-us), 0)
+		}
+
+		for b != 0 {
+			ml.add(NewSimpleMove(ksq, b.popLSB()))
+		}
+
+		if (gt == Quiets || gt == NonEvasions) && p.CanCastle(us.CastlingRights()) {
+			for _, cr := range []CastlingRights{us.CastlingRights() & KingSide, us.CastlingRights() & QueenSide} {
+				if !p.CastlingImpeded(cr) && p.CanCastle(cr) {
+					ml.add(NewMove(ksq, p.CastlingRookSquare(cr), Knight, Castling))
+				}
+			}
+		}
+	}
+}
+
+func (p *Position) generateMoves(pt PieceType, checks bool, ml *MoveList, target Bitboard) {
+	us := p.sideToMove
+	bb := p.Pieces(us, pt)
+
+	for bb != 0 {
+		from := bb.popLSB()
+		b := AttacksBB(pt, from, p.PiecesByType(AllPieces)) & target
+
+		// To check, you either move freely a blocker or make a direct check.
+		if checks && (pt == Queen || p.KingBlockers(1-us)&from.Bitboard() == 0) {
+			b &= p.CheckSquares(pt)
+		}
+
+		for b != 0 {
+			ml.add(NewSimpleMove(from, b.popLSB()))
+		}
+	}
+}
+
+func (p *Position) generatePawnMoves(gt GenType, ml *MoveList, target Bitboard) {
+	us := p.sideToMove
+	them := 1 - us
+
+	rank7BB := Rank7BB
+	rank3BB := Rank3BB
+	up, upRight, upLeft := North, NorthEast, NorthWest
+	if us == Black {
+		rank7BB = Rank2BB
+		rank3BB = Rank6BB
+		up, upRight, upLeft = South, SouthWest, SouthEast
+	}
+
+	emptySquares := ^p.PiecesByType(AllPieces)
+	enemies := p.PiecesByColor(them)
+	if gt == Evasions {
+		enemies = p.Checkers()
+	}
+
+	pawnsOn7 := p.Pieces(us, Pawn) & rank7BB
+	pawnsNotOn7 := p.Pieces(us, Pawn) & ^rank7BB
+
+	// Single and double pawn pushes, no promotions
+	if gt != Captures {
+		b1 := pawnsNotOn7.Shift(up) & emptySquares
+		b2 := (b1 & rank3BB).Shift(up) & emptySquares
+
+		if gt == Evasions {
+			b1 &= target
+			b2 &= target
+		}
+
+		if gt == QuietChecks {
+			// To make a quiet check, you either make a direct check by pushing a
+			// pawn or push a blocker pawn that is not on the same file as the
+			// enemy king.
+			ksq := p.KingSquare(them)
+			dcCandidatePawns := p.KingBlockers(them) & ^ksq.File().Bitboard()
+			b1 &= PawnAttacks[them][ksq] | dcCandidatePawns.Shift(up)
+			b2 &= PawnAttacks[them][ksq] | dcCandidatePawns.Shift(up+up)
+		}
+
+		for b1 != 0 {
+			to := b1.popLSB()
+			ml.add(NewSimpleMove(to-Square(up), to))
+		}
+		for b2 != 0 {
+			to := b2.popLSB()
+			ml.add(NewSimpleMove(to-Square(up+up), to))
+		}
+	}
+
+	// Promotions and underpromotions
+	if pawnsOn7 != 0 {
+		b1 := pawnsOn7.Shift(upRight) & enemies
+		b2 := pawnsOn7.Shift(upLeft) & enemies
+		b3 := pawnsOn7.Shift(up) & emptySquares
+
+		if gt == Evasions {
+			b3 &= target
+		}
+
+		for b1 != 0 {
+			makePromotions(gt, upRight, ml, b1.popLSB())
+		}
+		for b2 != 0 {
+			makePromotions(gt, upLeft, ml, b2.popLSB())
+		}
+		for b3 != 0 {
+			makePromotions(gt, up, ml, b3.popLSB())
+		}
+	}
+
+	// Standard and en passant captures
+	if gt == Captures || gt == Evasions || gt == NonEvasions {
+		b1 := pawnsNotOn7.Shift(upRight) & enemies
+		b2 := pawnsNotOn7.Shift(upLeft) & enemies
+
+		for b1 != 0 {
+			to := b1.popLSB()
+			ml.add(NewSimpleMove(to-Square(upRight), to))
+		}
+		for b2 != 0 {
+			to := b2.popLSB()
+			ml.add(NewSimpleMove(to-Square(upLeft), to))
+		}
+
+		if ep := p.EpSquare(); ep != SquareNone {
+			// An en passant capture cannot resolve a discovered check
+			if gt == Evasions && target&(ep+Square(up)).Bitboard() != 0 {
+				return
+			}
+
+			b1 = pawnsNotOn7 & PawnAttacks[them][ep]
+			for b1 != 0 {
+				ml.add(NewMove(b1.popLSB(), ep, Knight, EnPassant))
+			}
+		}
+	}
+}
+
+func makePromotions(gt GenType, d Direction, ml *MoveList, to Square) {
+	from := to - Square(d)
+
+	if gt == Captures || gt == Evasions || gt == NonEvasions {
+		ml.add(NewMove(from, to, Queen, Promotion))
+	}
+
+	if gt == Quiets || gt == Evasions || gt == NonEvasions {
+		ml.add(NewMove(from, to, Rook, Promotion))
+		ml.add(NewMove(from, to, Bishop, Promotion))
+		ml.add(NewMove(from, to, Knight, Promotion))
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import "testing"
+
+// generate() returns how many times every move of type gt is generated.
+func generate(pos *Position, gt GenType) map[Move]int {
+	var ml MoveList
+	pos.Generate(gt, &ml)
+	moves := make(map[Move]int)
+	for i := 0; i < ml.Len(); i++ {
+		moves[ml.Move(i)]++
+	}
+	return moves
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		fen   string
+		legal int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 20},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 48},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 14},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 44},
+		{"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", 46},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", 21},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", 7},
+
+		// In check
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 6},
+		{"4k3/8/8/8/8/8/4r3/R3K3 w Q - 0 1", 3},
+		{"4k3/8/8/8/1b6/8/4r3/R3K1N1 w Q - 0 1", 3},
+		{"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1", 9},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", 0},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every move is generated once
+		lists := []GenType{Legal, Evasions}
+		if pos.Checkers() == 0 {
+			lists = []GenType{Legal, Captures, Quiets, QuietChecks, NonEvasions}
+		}
+		moves := make(map[GenType]map[Move]int)
+		for _, gt := range lists {
+			moves[gt] = generate(pos, gt)
+			for m, n := range moves[gt] {
+				if n != 1 {
+					t.Errorf("%v: generation type %v: %v generated %d times", tt.fen, gt, m, n)
+				}
+			}
+		}
+
+		if len(moves[Legal]) != tt.legal {
+			t.Errorf("%v: got %d legal moves, want %d", tt.fen, len(moves[Legal]), tt.legal)
+		}
+
+		// In check the legal moves are the legal evasions
+		if pos.Checkers() != 0 {
+			for m := range moves[Legal] {
+				if moves[Evasions][m] == 0 {
+					t.Errorf("%v: the legal move %v is not an evasion", tt.fen, m)
+				}
+			}
+			continue
+		}
+
+		// Otherwise the captures and the quiet moves are the non evasions,
+		// and include the legal moves
+		for m := range moves[NonEvasions] {
+			if moves[Captures][m]+moves[Quiets][m] != 1 {
+				t.Errorf("%v: the non evasion %v is generated %d times as a capture and %d times as a quiet move",
+					tt.fen, m, moves[Captures][m], moves[Quiets][m])
+			}
+		}
+		if len(moves[Captures])+len(moves[Quiets]) != len(moves[NonEvasions]) {
+			t.Errorf("%v: %d captures and %d quiet moves, %d non evasions", tt.fen,
+				len(moves[Captures]), len(moves[Quiets]), len(moves[NonEvasions]))
+		}
+		for m := range moves[QuietChecks] {
+			if moves[Quiets][m] == 0 {
+				t.Errorf("%v: the quiet check %v is not a quiet move", tt.fen, m)
+			}
+		}
+		for m := range moves[Legal] {
+			if moves[NonEvasions][m] == 0 {
+				t.Errorf("%v: the legal move %v is not a non evasion", tt.fen, m)
+			}
+		}
+	}
+}
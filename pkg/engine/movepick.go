@@ -0,0 +1,296 @@
+package engine
+
+type pickStage int
+
+const (
+	mainTT pickStage = iota
+	captureInit
+	goodCapture
+	refutation
+	quietInit
+	quiet
+	badCapture
+
+	evasionTT
+	evasionInit
+	evasion
+
+	qsearchTT
+	qcaptureInit
+	qcapture
+	qcheckInit
+	qcheck
+)
+
+// movePicker returns the pseudo-legal moves of a position one at a time, from
+// the one most likely to cause a cutoff. The moves are generated in stages,
+// so that when the first moves cause a cutoff the rest are never generated:
+//
+// main search  TT move, good captures, killers and countermove, quiets, bad captures
+// evasions     TT move, evasions
+// qsearch      TT move, captures, quiet checks
+type movePicker struct {
+	pos   *Position
+	h     *history
+	stage pickStage
+
+	ttMove      Move
+	refutations [3]Move // the killers and the countermove
+	// contHist are the continuation histories of the moves 1, 2 and 4 plies
+	// ago
+	contHist [3]*pieceToHistory
+
+	ml       MoveList
+	cur      int
+	endBad   int // bad captures are moved to the start of the list
+	refIndex int
+	checks   bool // whether the qsearch returns the quiet checks
+}
+
+// newMovePicker() returns a picker for the main search.
+func newMovePicker(pos *Position, h *history, ttMove Move, killers [2]Move, counter Move, contHist [3]*pieceToHistory) *movePicker {
+	mp := &movePicker{pos: pos, h: h, contHist: contHist}
+
+	if pos.Checkers() != 0 {
+		mp.stage = evasionTT
+	} else {
+		mp.stage = mainTT
+		mp.refutations = [3]Move{killers[0], killers[1], counter}
+	}
+
+	mp.ttMove = ttMove
+	if ttMove == MoveNone || !pos.IsMovePseudoLegal(ttMove) {
+		mp.ttMove = MoveNone
+		mp.stage++
+	}
+
+	return mp
+}
+
+// newQSearchPicker() returns a picker for the quiescence search, it only
+// returns the captures and the quiet checks if checks is set, or all the
+// evasions when in check.
+func newQSearchPicker(pos *Position, h *history, ttMove Move, contHist [3]*pieceToHistory, checks bool) *movePicker {
+	mp := &movePicker{pos: pos, h: h, contHist: contHist, checks: checks}
+
+	if pos.Checkers() != 0 {
+		mp.stage = evasionTT
+	} else {
+		mp.stage = qsearchTT
+	}
+
+	// Only a TT move that the captures generator would return is searched
+	mp.ttMove = ttMove
+	if ttMove == MoveNone || !pos.IsMovePseudoLegal(ttMove) ||
+		(mp.stage == qsearchTT && !isCaptureStage(pos, ttMove)) {
+		mp.ttMove = MoveNone
+		mp.stage++
+	}
+
+	return mp
+}
+
+// next() returns the next move, or MoveNone when there are no more moves.
+// If skipQuiets is set the quiet moves that have not been returned yet are
+// skipped.
+func (mp *movePicker) next(skipQuiets bool) Move {
+	for {
+		switch mp.stage {
+		case mainTT, evasionTT, qsearchTT:
+			mp.stage++
+			return mp.ttMove
+
+		case captureInit, qcaptureInit:
+			mp.ml.Reset()
+			mp.pos.Generate(Captures, &mp.ml)
+			mp.scoreCaptures(mp.ml.Slice())
+			mp.cur, mp.endBad = 0, 0
+			mp.stage++
+
+		case goodCapture:
+			for mp.cur < mp.ml.size {
+				m := mp.pickBest()
+				if m == mp.ttMove {
+					continue
+				}
+				if mp.isGoodCapture(m) {
+					return m
+				}
+				// Keep the losing captures for the last stage
+				mp.ml.moves[mp.endBad] = mp.ml.moves[mp.cur-1]
+				mp.endBad++
+			}
+			mp.stage++
+
+		case refutation:
+			// The killers and the countermove are quiet moves too
+			for !skipQuiets && mp.refIndex < len(mp.refutations) {
+				m := mp.refutations[mp.refIndex]
+				mp.refIndex++
+				if mp.isRefutation(m) {
+					return m
+				}
+			}
+			mp.stage++
+
+		case quietInit:
+			if !skipQuiets {
+				mp.ml.size = mp.endBad
+				mp.pos.Generate(Quiets, &mp.ml)
+				mp.scoreQuiets(mp.ml.moves[mp.endBad:mp.ml.size])
+			}
+			mp.cur = mp.endBad
+			mp.stage++
+
+		case quiet:
+			for !skipQuiets && mp.cur < mp.ml.size {
+				m := mp.pickBest()
+				if m != mp.ttMove && !mp.isReturnedRefutation(m) {
+					return m
+				}
+			}
+			mp.cur = 0
+			mp.stage++
+
+		case badCapture:
+			if mp.cur < mp.endBad {
+				mp.cur++
+				return mp.ml.moves[mp.cur-1].Move
+			}
+			return MoveNone
+
+		case evasionInit:
+			mp.ml.Reset()
+			mp.pos.Generate(Evasions, &mp.ml)
+			mp.scoreEvasions(mp.ml.Slice())
+			mp.cur = 0
+			mp.stage++
+
+		case evasion, qcapture:
+			for mp.cur < mp.ml.size {
+				if m := mp.pickBest(); m != mp.ttMove {
+					return m
+				}
+			}
+			if mp.stage == evasion || !mp.checks {
+				return MoveNone
+			}
+			mp.stage++
+
+		case qcheckInit:
+			mp.ml.Reset()
+			mp.pos.Generate(QuietChecks, &mp.ml)
+			mp.cur = 0
+			mp.stage++
+
+		case qcheck:
+			for mp.cur < mp.ml.size {
+				mp.cur++
+				if m := mp.ml.moves[mp.cur-1].Move; m != mp.ttMove {
+					return m
+				}
+			}
+			return MoveNone
+		}
+	}
+}
+
+// pickBest() swaps the best scored move of the remaining ones with the
+// current one and returns it.
+func (mp *movePicker) pickBest() Move {
+	moves := mp.ml.moves[:mp.ml.size]
+	best := mp.cur
+	for i := mp.cur + 1; i < len(moves); i++ {
+		if moves[i].Value > moves[best].Value {
+			best = i
+		}
+	}
+	moves[mp.cur], moves[best] = moves[best], moves[mp.cur]
+	mp.cur++
+	return moves[mp.cur-1].Move
+}
+
+// isGoodCapture() tests whether a capture does not lose material by static
+// exchange evaluation, with a margin for captures scored highly.
+func (mp *movePicker) isGoodCapture(m Move) bool {
+	return mp.pos.SEE(m, Value(-mp.ml.moves[mp.cur-1].Value/18))
+}
+
+// isRefutation() tests whether a killer or the countermove can be played,
+// it must be a quiet pseudo-legal move not returned already.
+func (mp *movePicker) isRefutation(m Move) bool {
+	if m == MoveNone || m == mp.ttMove || !mp.pos.IsMovePseudoLegal(m) || isCaptureStage(mp.pos, m) {
+		return false
+	}
+	for i := 0; i < mp.refIndex-1; i++ {
+		if mp.refutations[i] == m {
+			return false
+		}
+	}
+	return true
+}
+
+// isCaptureStage() tests whether m is generated with the captures: captures
+// and queen promotions, while underpromotions are generated with the quiets.
+func isCaptureStage(pos *Position, m Move) bool {
+	if m.Type() == Promotion {
+		return m.PromotionType() == Queen
+	}
+	return pos.IsMoveCapture(m)
+}
+
+func (mp *movePicker) isReturnedRefutation(m Move) bool {
+	for _, r := range mp.refutations {
+		if r == m {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreCaptures() scores the captures by the value of the captured piece
+// (MVV), then of the capturing piece (LVA), and by their capture history. The
+// king has no value, it only captures pieces that are not defended.
+func (mp *movePicker) scoreCaptures(moves []ExtMove) {
+	for i := range moves {
+		m := moves[i].Move
+		pc := mp.pos.MovedPiece(m)
+		captured := mp.pos.CapturedPiece(m).Type()
+		moves[i].Value = 7*int(PieceValue[captured]) - int(PieceValue[pc.Type()])/4 +
+			int(mp.h.capture[pc][m.ToSquare()][captured])
+	}
+}
+
+// scoreQuiets() scores the quiet moves by their butterfly and continuation
+// histories, the direct checks first so they are not skipped by the late
+// move pruning.
+func (mp *movePicker) scoreQuiets(moves []ExtMove) {
+	us := mp.pos.SideToMove()
+	for i := range moves {
+		m := moves[i].Move
+		pc := mp.pos.MovedPiece(m)
+		to := m.ToSquare()
+		moves[i].Value = int(mp.h.main[us][fromTo(m)]) +
+			2*int(mp.contHist[0][pc][to]) +
+			int(mp.contHist[1][pc][to]) +
+			int(mp.contHist[2][pc][to])
+		if mp.pos.CheckSquares(pc.Type())&to.Bitboard() != 0 {
+			moves[i].Value += 1 << 20
+		}
+	}
+}
+
+// scoreEvasions() scores the captures first, by MVV-LVA, then the quiet
+// moves by history.
+func (mp *movePicker) scoreEvasions(moves []ExtMove) {
+	us := mp.pos.SideToMove()
+	for i := range moves {
+		m := moves[i].Move
+		pc := mp.pos.MovedPiece(m)
+		if mp.pos.IsMoveCapture(m) {
+			moves[i].Value = 1<<28 + int(PieceValue[mp.pos.CapturedPiece(m).Type()]) - int(pc.Type())
+		} else {
+			moves[i].Value = int(mp.h.main[us][fromTo(m)]) + int(mp.contHist[0][pc][m.ToSquare()])
+		}
+	}
+}
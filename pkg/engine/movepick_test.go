@@ -0,0 +1,69 @@
+package engine
+
+import "testing"
+
+func TestMovePickerSkipQuiets(t *testing.T) {
+	pos, err := NewPosition("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHistory()
+	var contHist [3]*pieceToHistory
+	for i := range contHist {
+		contHist[i] = &h.continuation[NoPiece][0]
+	}
+
+	// The killers and the countermove are quiet, so they are skipped too
+	killers := [2]Move{pos.NewUCIMove("a2a3"), pos.NewUCIMove("e1g1")}
+	counter := pos.NewUCIMove("f3g3")
+	mp := newMovePicker(pos, h, pos.NewUCIMove("e2a6"), killers, counter, contHist)
+
+	captures := 0
+	for m := mp.next(true); m != MoveNone; m = mp.next(true) {
+		if !isCaptureStage(pos, m) {
+			t.Errorf("next(true) returned the quiet move %v", m.UCIString(false))
+		}
+		captures++
+	}
+
+	var ml MoveList
+	pos.Generate(Captures, &ml)
+	if captures != ml.Len() {
+		t.Errorf("next(true) returned %d captures, want %d", captures, ml.Len())
+	}
+}
+
+func TestMovePickerMVVLVA(t *testing.T) {
+	fens := []string{
+		"4k3/8/2q1r3/3P4/1N3B2/8/2Q5/2R2K2 w - - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	}
+
+	for _, fen := range fens {
+		pos, err := NewPosition(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := newHistory()
+		var contHist [3]*pieceToHistory
+		for i := range contHist {
+			contHist[i] = &h.continuation[NoPiece][0]
+		}
+
+		// Without history the captures of the most valuable victim come
+		// first, by the least valuable attacker
+		mp := newQSearchPicker(pos, h, MoveNone, contHist, false)
+		prev := MoveNone
+		for m := mp.next(false); m != MoveNone; m = mp.next(false) {
+			if prev != MoveNone {
+				pv, v := PieceValue[pos.CapturedPiece(prev).Type()], PieceValue[pos.CapturedPiece(m).Type()]
+				pa, a := PieceValue[pos.MovedPiece(prev).Type()], PieceValue[pos.MovedPiece(m).Type()]
+				if pv < v || (pv == v && pa > a) {
+					t.Errorf("%v: %v before %v", fen, prev.UCIString(false), m.UCIString(false))
+				}
+			}
+			prev = m
+		}
+	}
+}
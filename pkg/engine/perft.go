@@ -0,0 +1,72 @@
+package engine
+
+import "sync/atomic"
+
+// Perft() returns the number of leaf nodes of the legal move tree of p up to
+// the given depth. It is used to validate the move generator and the make and
+// unmake of moves against known results.
+func Perft(p *Position, depth int) uint64 {
+	return perft(p, depth, nil)
+}
+
+// perft() is Perft() that gives up once stop is set, the count is then
+// partial.
+func perft(p *Position, depth int, stop *int32) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+
+	var ml MoveList
+	p.Generate(Legal, &ml)
+
+	// Bulk counting, the moves of the last ply do not need to be made
+	if depth == 1 {
+		return uint64(ml.Len())
+	}
+
+	var nodes uint64
+	for i := 0; i < ml.Len(); i++ {
+		// Checking the stop flag near the leaves is too slow
+		if stop != nil && depth > 2 && atomic.LoadInt32(stop) != 0 {
+			break
+		}
+		m := ml.Move(i)
+		p.DoMove(m)
+		nodes += perft(p, depth-1, stop)
+		p.UndoMove(m)
+	}
+
+	return nodes
+}
+
+type MoveCount struct {
+	Move  Move
+	Nodes uint64
+}
+
+// Divide() is like Perft() but returns the number of leaf nodes under every
+// legal root move, in move generation order.
+func Divide(p *Position, depth int) []MoveCount {
+	return divide(p, depth, nil)
+}
+
+// divide() is Divide() that gives up once stop is set, the counts are then
+// partial.
+func divide(p *Position, depth int, stop *int32) []MoveCount {
+	if depth <= 0 {
+		return nil
+	}
+
+	var ml MoveList
+	p.Generate(Legal, &ml)
+
+	res := make([]MoveCount, 0, ml.Len())
+	for i := 0; i < ml.Len(); i++ {
+		m := ml.Move(i)
+		p.DoMove(m)
+		res = append(res, MoveCount{Move: m, Nodes: perft(p, depth-1, stop)})
+		p.UndoMove(m)
+	}
+
+	return res
+}
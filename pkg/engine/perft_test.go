@@ -0,0 +1,72 @@
+package engine
+
+import "testing"
+
+// The known perft results, from https://www.chessprogramming.org/Perft_Results
+var perftTests = []struct {
+	name  string
+	fen   string
+	nodes []uint64 // the nodes of depth 1, 2 and so on
+}{
+	{"startpos", StartFEN, []uint64{20, 400, 8902, 197281, 4865609}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624, 11030083}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467, 422333, 15833292}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []uint64{6, 264, 9467, 422333, 15833292}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890, 3894594}},
+	{"chess960 1", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672, 8146062}},
+	{"chess960 2", "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002, 667366, 16253601}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tt := range perftTests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, want := range tt.nodes {
+			// The deep perfts take seconds
+			if testing.Short() && want > 1000000 {
+				break
+			}
+			if got := Perft(pos, i+1); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	want := map[string]uint64{
+		"a2a3": 380, "b2b3": 420, "c2c3": 420, "d2d3": 539, "e2e3": 599, "f2f3": 380, "g2g3": 420, "h2h3": 380,
+		"a2a4": 420, "b2b4": 421, "c2c4": 441, "d2d4": 560, "e2e4": 600, "f2f4": 401, "g2g4": 421, "h2h4": 420,
+		"b1a3": 400, "b1c3": 440, "g1f3": 440, "g1h3": 400,
+	}
+	pos, _ := NewPosition(StartFEN)
+	res := Divide(pos, 3)
+	if len(res) != len(want) {
+		t.Errorf("startpos: got %d moves, want %d", len(res), len(want))
+	}
+	for _, mc := range res {
+		if n := want[mc.Move.String()]; mc.Nodes != n {
+			t.Errorf("startpos: %v: got %v, want %v", mc.Move, mc.Nodes, n)
+		}
+	}
+
+	// The counts add up to the perft of the position
+	for _, tt := range perftTests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var nodes uint64
+		for _, mc := range Divide(pos, 3) {
+			nodes += mc.Nodes
+		}
+		if nodes != tt.nodes[2] {
+			t.Errorf("%s: got %v, want %v", tt.name, nodes, tt.nodes[2])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -9,6 +10,8 @@ import (
 type State struct {
 	// copied when making a move
 
+	pawnKey        Key
+	materialKey    Key
 	castlingRights int
 	rule50         int
 	pliesFromNull  int
@@ -16,6 +19,7 @@ type State struct {
 
 	// recalculated
 
+	key           Key
 	checkersBB    Bitboard
 	kingBlockers  [ColorNB]Bitboard
 	pinners       [ColorNB]Bitboard
@@ -28,6 +32,8 @@ type State struct {
 
 func (s State) Copy() *State {
 	return &State{
+		pawnKey:        s.pawnKey,
+		materialKey:    s.materialKey,
 		castlingRights: s.castlingRights,
 		rule50:         s.rule50,
 		pliesFromNull:  s.pliesFromNull,
@@ -46,67 +52,463 @@ type Position struct {
 	castlingPath       [CastlingRightsNB]Bitboard
 	state              *State
 	gamePly            int
+	chess960           bool
 }
 
-func NewPosition(fen string) *Position {
+// Copy() returns a deep copy of the position, including the states of the
+// moves played to reach it, so the copy can be used by another goroutine.
+func (p *Position) Copy() *Position {
+	c := *p
+	for st := &c.state; *st != nil; st = &(*st).prevState {
+		newSt := **st
+		*st = &newSt
+	}
+	return &c
+}
+
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+const pieceToChar = " PNBRQK  pnbrqk"
+
+// NewPosition() initializes a Position from a FEN string. The halfmove clock
+// and fullmove number fields may be omitted. Castling rights are accepted in
+// standard, X-FEN and Shredder-FEN notation, the latter also marking the
+// position as Chess960.
+func NewPosition(fen string) (*Position, error) {
 	p := &Position{
-		state: &State{},
+		state: &State{epSquare: SquareNone, pawnKey: zobristNoPawns},
 	}
 
 	str := strings.Fields(fen)
+	if len(str) < 4 || len(str) > 6 {
+		return nil, fmt.Errorf("invalid fen %q: expected 4 to 6 fields, got %d", fen, len(str))
+	}
 
 	// 1. Piece placement
-	sq := SquareA8
-	pieceToChar := " PNBRQK  pnbrqk"
-	for _, r := range str[0] {
-		switch {
-		case unicode.IsDigit(r):
-			sq += Square((r - '0') * rune(East))
-		case r == '/':
-			sq += Square(2 * South)
-		default:
-			if idx := strings.Index(pieceToChar, string(r)); idx != -1 {
-				p.PutPiece(Piece(idx), sq)
-				sq++
+	ranks := strings.Split(str[0], "/")
+	if len(ranks) != int(RankNB) {
+		return nil, fmt.Errorf("invalid fen %q: expected %d ranks, got %d", fen, RankNB, len(ranks))
+	}
+	for i, rs := range ranks {
+		r := Rank8 - Rank(i)
+		f := FileA
+		for _, c := range rs {
+			if c >= '1' && c <= '8' {
+				f += File(c - '0')
+				continue
+			}
+			idx := strings.IndexRune(pieceToChar, c)
+			if idx == -1 || c == ' ' {
+				return nil, fmt.Errorf("invalid fen %q: unknown piece character %q on rank %v", fen, c, r)
+			}
+			if f >= FileNB {
+				return nil, fmt.Errorf("invalid fen %q: rank %v does not describe exactly %d squares", fen, r, FileNB)
 			}
+			p.PutPiece(Piece(idx), NewSquare(f, r))
+			f++
 		}
+		if f != FileNB {
+			return nil, fmt.Errorf("invalid fen %q: rank %v does not describe exactly %d squares", fen, r, FileNB)
+		}
+	}
+
+	for c := White; c < ColorNB; c++ {
+		if n := p.pieceCount[NewPiece(c, King)]; n != 1 {
+			return nil, fmt.Errorf("invalid fen %q: %v has %d kings", fen, c, n)
+		}
+	}
+	if p.PiecesByType(Pawn)&(Rank1BB|Rank8BB) != 0 {
+		return nil, fmt.Errorf("invalid fen %q: pawns on the first or last rank", fen)
 	}
 
 	// 2. Active color
-	p.sideToMove = White
-	if str[1] == "b" {
+	switch str[1] {
+	case "w":
+		p.sideToMove = White
+	case "b":
 		p.sideToMove = Black
+	default:
+		return nil, fmt.Errorf("invalid fen %q: invalid active color %q", fen, str[1])
+	}
+	if p.AttackersTo(p.KingSquare(1-p.sideToMove))&p.PiecesByColor(p.sideToMove) != 0 {
+		return nil, fmt.Errorf("invalid fen %q: the side not to move is in check", fen)
+	}
+
+	// 3. Castling availability
+	if str[2] != "-" {
+		for _, c := range str[2] {
+			if err := p.parseCastlingRight(c); err != nil {
+				return nil, fmt.Errorf("invalid fen %q: %w", fen, err)
+			}
+		}
+	}
+
+	// 4. En passant square. It is only kept when the side to move can
+	// actually capture, the same rule that doMove() uses.
+	if str[3] != "-" {
+		sq, err := ParseSquare(str[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid fen %q: invalid en passant square: %w", fen, err)
+		}
+		us := p.sideToMove
+		them := 1 - us
+		if sq.RelativeRank(us) != Rank6 {
+			return nil, fmt.Errorf("invalid fen %q: en passant square %v is on the wrong rank", fen, sq)
+		}
+		if PawnAttacks[them][sq]&p.Pieces(us, Pawn) != 0 &&
+			p.Pieces(them, Pawn)&(sq+Square(PawnPush(them))).Bitboard() != 0 &&
+			p.PiecesByType(AllPieces)&(sq.Bitboard()|(sq+Square(PawnPush(us))).Bitboard()) == 0 {
+			p.state.epSquare = sq
+		}
+	}
+
+	// 5-6. Halfmove clock and fullmove number
+	fullmove := 1
+	if len(str) > 4 {
+		n, err := strconv.Atoi(str[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid fen %q: invalid halfmove clock %q", fen, str[4])
+		}
+		p.state.rule50 = n
+	}
+	if len(str) > 5 {
+		n, err := strconv.Atoi(str[5])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid fen %q: invalid fullmove number %q", fen, str[5])
+		}
+		fullmove = n
+	}
+
+	// Convert from fullmove starting from 1 to gamePly starting from 0,
+	// handle also common incorrect FEN with fullmove = 0.
+	p.gamePly = 2 * (fullmove - 1)
+	if p.gamePly < 0 {
+		p.gamePly = 0
+	}
+	if p.sideToMove == Black {
+		p.gamePly++
+	}
+
+	p.setState()
+
+	return p, nil
+}
+
+// parseCastlingRight() handles a single character of the castling field.
+func (p *Position) parseCastlingRight(c rune) error {
+	col := White
+	if unicode.IsLower(c) {
+		col = Black
+	}
+	rook := NewPiece(col, Rook)
+	backRank := Rank1.RelativeRank(col)
+	ksq := p.KingSquare(col)
+	if ksq.Rank() != backRank {
+		return fmt.Errorf("castling right %q but the %v king is not on its first rank", c, col)
+	}
+
+	rsq := SquareNone
+	switch token := unicode.ToUpper(c); {
+	case token == 'K':
+		for s := NewSquare(FileH, backRank); s > ksq; s-- {
+			if p.PieceOn(s) == rook {
+				rsq = s
+				break
+			}
+		}
+	case token == 'Q':
+		for s := NewSquare(FileA, backRank); s < ksq; s++ {
+			if p.PieceOn(s) == rook {
+				rsq = s
+				break
+			}
+		}
+	case token >= 'A' && token <= 'H':
+		if s := NewSquare(File(token-'A'), backRank); p.PieceOn(s) == rook && s != ksq {
+			rsq = s
+		}
+		p.chess960 = true
+	default:
+		return fmt.Errorf("invalid castling character %q", c)
+	}
+
+	if rsq == SquareNone {
+		return fmt.Errorf("castling right %q without a matching %v rook", c, col)
+	}
+
+	if ksq.File() != FileE || (rsq.File() != FileA && rsq.File() != FileH) {
+		p.chess960 = true
+	}
+
+	p.setCastlingRight(col, rsq)
+
+	return nil
+}
+
+func (p *Position) setCastlingRight(c Color, rfrom Square) {
+	kfrom := p.KingSquare(c)
+	cr := c.CastlingRights() & QueenSide
+	kto := SquareC1.RelativeSquare(c)
+	rto := SquareD1.RelativeSquare(c)
+	if kfrom < rfrom {
+		cr = c.CastlingRights() & KingSide
+		kto = SquareG1.RelativeSquare(c)
+		rto = SquareF1.RelativeSquare(c)
+	}
+
+	p.state.castlingRights |= int(cr)
+	p.castlingRightsMask[kfrom] |= int(cr)
+	p.castlingRightsMask[rfrom] |= int(cr)
+	p.castlingRookSquare[cr] = rfrom
+	p.castlingPath[cr] = (BetweenBB[rfrom][rto] | BetweenBB[kfrom][kto]) & ^(kfrom.Bitboard() | rfrom.Bitboard())
+}
+
+// setState() computes the State fields that are not copied when making a
+// move. It is only used when setting up a new Position.
+func (p *Position) setState() {
+	p.state.key = p.computeKey()
+	p.state.checkersBB = p.AttackersTo(p.KingSquare(p.sideToMove)) & p.PiecesByColor(1-p.sideToMove)
+	p.setCheckInfo()
+}
+
+// computeKey() computes the hash key of the position from scratch.
+func (p *Position) computeKey() Key {
+	var k Key
+
+	for b := p.PiecesByType(AllPieces); b != 0; {
+		s := b.popLSB()
+		k ^= zobristPsq[p.PieceOn(s)][s]
+	}
+
+	if p.state.epSquare != SquareNone {
+		k ^= zobristEnpassant[p.state.epSquare.File()]
+	}
+
+	if p.sideToMove == Black {
+		k ^= zobristSide
+	}
+
+	return k ^ zobristCastling[p.state.castlingRights]
+}
+
+// computePawnKey() computes the hash key of the pawns and kings from scratch.
+func (p *Position) computePawnKey() Key {
+	k := zobristNoPawns
+
+	for b := p.PiecesByType(Pawn) | p.PiecesByType(King); b != 0; {
+		s := b.popLSB()
+		k ^= zobristPsq[p.PieceOn(s)][s]
 	}
 
-	// TODO: continue to parse the rest of the fen string
+	return k
+}
+
+// computeMaterialKey() computes the hash key of the material from scratch.
+func (p *Position) computeMaterialKey() Key {
+	var k Key
+
+	for c := White; c < ColorNB; c++ {
+		for pt := Pawn; pt <= King; pt++ {
+			pc := NewPiece(c, pt)
+			for cnt := 0; cnt < p.pieceCount[pc]; cnt++ {
+				k ^= zobristPsq[pc][cnt]
+			}
+		}
+	}
+
+	return k
+}
+
+// validate() performs some consistency checks for the position object and
+// returns an error describing the first failed check. It is meant to be
+// helpful when debugging.
+func (p *Position) validate() error {
+	if k := p.computeKey(); k != p.state.key {
+		return fmt.Errorf("position %v: key %x does not match the computed key %x", p.Fen(), p.state.key, k)
+	}
+
+	if k := p.computePawnKey(); k != p.state.pawnKey {
+		return fmt.Errorf("position %v: pawn key %x does not match the computed key %x", p.Fen(), p.state.pawnKey, k)
+	}
+
+	if k := p.computeMaterialKey(); k != p.state.materialKey {
+		return fmt.Errorf("position %v: material key %x does not match the computed key %x", p.Fen(), p.state.materialKey, k)
+	}
+
+	return nil
+}
+
+// setCheckInfo() sets king attacks to detect if a move gives check.
+func (p *Position) setCheckInfo() {
+	p.state.kingBlockers[White] = p.sliderBlockers(p.PiecesByColor(Black), p.KingSquare(White), &p.state.pinners[Black])
+	p.state.kingBlockers[Black] = p.sliderBlockers(p.PiecesByColor(White), p.KingSquare(Black), &p.state.pinners[White])
+
+	ksq := p.KingSquare(1 - p.sideToMove)
+	occupied := p.PiecesByType(AllPieces)
 
-	return p
+	p.state.checkSquares[Pawn] = PawnAttacks[1-p.sideToMove][ksq]
+	p.state.checkSquares[Knight] = AttacksBB(Knight, ksq, occupied)
+	p.state.checkSquares[Bishop] = AttacksBB(Bishop, ksq, occupied)
+	p.state.checkSquares[Rook] = AttacksBB(Rook, ksq, occupied)
+	p.state.checkSquares[Queen] = p.state.checkSquares[Bishop] | p.state.checkSquares[Rook]
+	p.state.checkSquares[King] = 0
 }
 
-func (p Position) Fen() string {
-	// TODO: implement
-	return ""
+// sliderBlockers() returns a bitboard of all the pieces (both colors) that
+// are blocking attacks on the square s from sliders. A piece blocks a slider
+// if removing that piece from the board would result in a position where
+// square s is attacked. For example, a king-attack blocking piece can be
+// either a pinned or a discovered check piece, according if its color is the
+// opposite or the same of the color of the slider. The pinners of the pieces
+// of the color of the piece on s are stored in pinners.
+func (p *Position) sliderBlockers(sliders Bitboard, s Square, pinners *Bitboard) Bitboard {
+	var blockers Bitboard
+	*pinners = 0
+
+	// Snipers are sliders that attack s when a piece and other snipers are removed
+	snipers := ((AttacksBB(Rook, s, 0) & (p.PiecesByType(Queen) | p.PiecesByType(Rook))) |
+		(AttacksBB(Bishop, s, 0) & (p.PiecesByType(Queen) | p.PiecesByType(Bishop)))) & sliders
+	occupancy := p.PiecesByType(AllPieces) ^ snipers
+
+	for snipers != 0 {
+		sniperSq := snipers.popLSB()
+		b := BetweenBB[s][sniperSq] & occupancy
+
+		if b != 0 && !b.MoreThanOne() {
+			blockers |= b
+			if b&p.PiecesByColor(p.PieceOn(s).Color()) != 0 {
+				*pinners |= sniperSq.Bitboard()
+			}
+		}
+	}
+
+	return blockers
+}
+
+// Fen() returns a FEN representation of the position. Castling rights of a
+// Chess960 position are written in Shredder-FEN notation.
+func (p *Position) Fen() string {
+	var sb strings.Builder
+
+	for r := Rank8; r >= Rank1; r-- {
+		for f := FileA; f <= FileH; f++ {
+			empty := 0
+			for ; f <= FileH && p.PieceOn(NewSquare(f, r)) == NoPiece; f++ {
+				empty++
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+			}
+			if f <= FileH {
+				sb.WriteByte(pieceToChar[p.PieceOn(NewSquare(f, r))])
+			}
+		}
+		if r > Rank1 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if p.sideToMove == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	castling := []struct {
+		cr CastlingRights
+		ch byte
+	}{{WhiteOO, 'K'}, {WhiteOOO, 'Q'}, {BlackOO, 'k'}, {BlackOOO, 'q'}}
+	for _, c := range castling {
+		if !p.CanCastle(c.cr) {
+			continue
+		}
+		ch := c.ch
+		if p.chess960 {
+			ch = byte('A' + p.CastlingRookSquare(c.cr).File())
+			if c.cr&BlackCastling != 0 {
+				ch += 'a' - 'A'
+			}
+		}
+		sb.WriteByte(ch)
+	}
+	if !p.CanCastle(AnyCastling) {
+		sb.WriteByte('-')
+	}
+
+	if p.state.epSquare == SquareNone {
+		sb.WriteString(" - ")
+	} else {
+		sb.WriteString(" " + strings.ToLower(p.state.epSquare.String()) + " ")
+	}
+
+	fullmove := 1 + (p.gamePly-int(p.sideToMove))/2
+	sb.WriteString(strconv.Itoa(p.state.rule50) + " " + strconv.Itoa(fullmove))
+
+	return sb.String()
 }
 
 // Position representation
 
-func (p Position) Pieces(c Color, pt PieceType) Bitboard {
+func (p *Position) Pieces(c Color, pt PieceType) Bitboard {
 	return p.byColorBB[c] & p.byTypeBB[pt]
 }
 
-func (p Position) PiecesByType(pt PieceType) Bitboard {
+func (p *Position) PiecesByType(pt PieceType) Bitboard {
 	return p.byTypeBB[pt]
 }
 
-func (p Position) PiecesByColor(c Color) Bitboard {
+func (p *Position) PiecesByColor(c Color) Bitboard {
 	return p.byColorBB[c]
 }
 
-func (p Position) PieceOn(s Square) Piece {
+// NonPawnMaterial() returns the value of the pieces of c, other than the
+// pawns and the king.
+func (p *Position) NonPawnMaterial(c Color) Value {
+	var v Value
+	for pt := Knight; pt <= Queen; pt++ {
+		v += Value(p.Pieces(c, pt).PopCount()) * PieceValue[pt]
+	}
+	return v
+}
+
+func (p *Position) PieceOn(s Square) Piece {
 	return p.board[s]
 }
 
-func (p Position) EpSquare() Square {
+func (p *Position) SideToMove() Color {
+	return p.sideToMove
+}
+
+// GamePly() returns the number of plies played since the start of the game,
+// as given by the fullmove number of the FEN.
+func (p *Position) GamePly() int {
+	return p.gamePly
+}
+
+func (p *Position) KingSquare(c Color) Square {
+	return p.Pieces(c, King).lsb()
+}
+
+func (p *Position) IsChess960() bool {
+	return p.chess960
+}
+
+func (p *Position) Key() Key {
+	return p.state.key
+}
+
+// PawnKey() returns the hash key of the pawns and kings of the position.
+func (p *Position) PawnKey() Key {
+	return p.state.pawnKey
+}
+
+// MaterialKey() returns a hash key that only depends on the number of pieces
+// of each type and color.
+func (p *Position) MaterialKey() Key {
+	return p.state.materialKey
+}
+
+func (p *Position) EpSquare() Square {
 	return p.state.epSquare
 }
 
@@ -117,6 +519,10 @@ func (p *Position) PutPiece(pc Piece, s Square) {
 	p.byColorBB[pc.Color()] |= s.Bitboard()
 	p.pieceCount[pc]++
 	p.pieceCount[NewPiece(Color(pc.Color()), AllPieces)]++
+	p.state.materialKey ^= zobristPsq[pc][p.pieceCount[pc]-1]
+	if pc.Type() == Pawn || pc.Type() == King {
+		p.state.pawnKey ^= zobristPsq[pc][s]
+	}
 }
 
 func (p *Position) RemovePiece(s Square) {
@@ -125,6 +531,10 @@ func (p *Position) RemovePiece(s Square) {
 	p.byTypeBB[pc.Type()] ^= s.Bitboard()
 	p.byColorBB[pc.Color()] ^= s.Bitboard()
 	p.board[s] = NoPiece
+	p.state.materialKey ^= zobristPsq[pc][p.pieceCount[pc]-1]
+	if pc.Type() == Pawn || pc.Type() == King {
+		p.state.pawnKey ^= zobristPsq[pc][s]
+	}
 	p.pieceCount[pc]--
 	p.pieceCount[NewPiece(Color(pc.Color()), AllPieces)]--
 }
@@ -137,101 +547,308 @@ func (p *Position) movePiece(from, to Square) {
 	p.byColorBB[pc.Color()] ^= fromTo
 	p.board[from] = NoPiece
 	p.board[to] = pc
+	if pc.Type() == Pawn || pc.Type() == King {
+		p.state.pawnKey ^= zobristPsq[pc][from] ^ zobristPsq[pc][to]
+	}
 }
 
 // Castling
 
-func (p Position) CastlingRights(c Color) CastlingRights {
-	return CastlingRights(c) & CastlingRights(p.state.castlingRights)
+func (p *Position) CastlingRights(c Color) CastlingRights {
+	return c.CastlingRights() & CastlingRights(p.state.castlingRights)
 }
 
-func (p Position) CanCastle(cr CastlingRights) bool {
+func (p *Position) CanCastle(cr CastlingRights) bool {
 	return CastlingRights(p.state.castlingRights)&cr != 0
 }
 
-func (p Position) CastlingImpeded(cr CastlingRights) bool {
+func (p *Position) CastlingImpeded(cr CastlingRights) bool {
 	return p.byTypeBB[AllPieces]&p.castlingPath[cr] != 0
 }
 
-func (p Position) CastlingRookSquare(cr CastlingRights) Square {
+func (p *Position) CastlingRookSquare(cr CastlingRights) Square {
 	return p.castlingRookSquare[cr]
 }
 
-func (p Position) doCastling(c Color, from, to Square) (rfrom, rto Square) {
-	// TODO: implement
-	return 0, 0
+// castlingSquares() returns the origin and destination squares of the rook
+// and the destination square of the king of a castling move. Castling is
+// encoded as "king captures friendly rook".
+func castlingSquares(c Color, from, to Square) (rfrom, rto, kto Square) {
+	if to > from {
+		return to, SquareF1.RelativeSquare(c), SquareG1.RelativeSquare(c)
+	}
+	return to, SquareD1.RelativeSquare(c), SquareC1.RelativeSquare(c)
+}
+
+func (p *Position) doCastling(c Color, from, to Square) (rfrom, rto Square) {
+	rfrom, rto, kto := castlingSquares(c, from, to)
+
+	// Remove both pieces first since squares could overlap in Chess960
+	p.RemovePiece(from)
+	p.RemovePiece(rfrom)
+	p.PutPiece(NewPiece(c, King), kto)
+	p.PutPiece(NewPiece(c, Rook), rto)
+
+	return rfrom, rto
 }
 
-func (p Position) undoCastling(c Color, from, to Square) (rfrom, rto Square) {
-	// TODO: implement
-	return 0, 0
+func (p *Position) undoCastling(c Color, from, to Square) (rfrom, rto Square) {
+	rfrom, rto, kto := castlingSquares(c, from, to)
+
+	p.RemovePiece(kto)
+	p.RemovePiece(rto)
+	p.PutPiece(NewPiece(c, King), from)
+	p.PutPiece(NewPiece(c, Rook), rfrom)
+
+	return rfrom, rto
 }
 
 // Checking
 
-func (p Position) Checkers() Bitboard {
+func (p *Position) Checkers() Bitboard {
 	return p.state.checkersBB
 }
 
-func (p Position) KingBlockers(c Color) Bitboard {
+func (p *Position) KingBlockers(c Color) Bitboard {
 	return p.state.kingBlockers[c]
 }
 
-func (p Position) CheckSquares(pt PieceType) Bitboard {
+func (p *Position) CheckSquares(pt PieceType) Bitboard {
 	return p.state.checkSquares[pt]
 }
 
-func (p Position) Pinners(c Color) Bitboard {
+func (p *Position) Pinners(c Color) Bitboard {
 	return p.state.pinners[c]
 }
 
 // Attacks to/from a given square
-func (p Position) AttackersTo(s Square) Bitboard {
+func (p *Position) AttackersTo(s Square) Bitboard {
 	return p.attackersTo(s, p.byTypeBB[AllPieces])
 }
 
-func (p Position) attackersTo(s Square, occupied Bitboard) Bitboard {
-	// TODO: implement
-	return 0
+func (p *Position) attackersTo(s Square, occupied Bitboard) Bitboard {
+	return (PawnAttacks[Black][s] & p.Pieces(White, Pawn)) |
+		(PawnAttacks[White][s] & p.Pieces(Black, Pawn)) |
+		(AttacksBB(Knight, s, occupied) & p.PiecesByType(Knight)) |
+		(AttacksBB(Rook, s, occupied) & (p.PiecesByType(Rook) | p.PiecesByType(Queen))) |
+		(AttacksBB(Bishop, s, occupied) & (p.PiecesByType(Bishop) | p.PiecesByType(Queen))) |
+		(AttacksBB(King, s, occupied) & p.PiecesByType(King))
 }
 
 // Properties of Moves
 
 // NewUCIMove() converts a string representing a move in coordinate notation
-// (g1f3, a7a8q) to the corresponding legal Move, if any.
-func (p Position) NewUCIMove(str string) Move {
-	// TODO: implement
+// (g1f3, a7a8q) to the corresponding legal Move, if any. In Chess960
+// castling moves are given as 'king captures rook' (e1h1).
+func (p *Position) NewUCIMove(str string) Move {
+	str = strings.ToLower(str)
+
+	var ml MoveList
+	p.Generate(Legal, &ml)
+	for i := 0; i < ml.Len(); i++ {
+		if m := ml.Move(i); m.UCIString(p.chess960) == str {
+			return m
+		}
+	}
+
 	return MoveNone
 }
 
-func (p Position) IsMoveLegal(m Move) bool {
-	// TODO: implement
-	return false
+// IsMoveLegal() tests whether a pseudo-legal move is legal.
+func (p *Position) IsMoveLegal(m Move) bool {
+	us := p.sideToMove
+	from := m.FromSquare()
+	to := m.ToSquare()
+
+	// En passant captures are a tricky special case. Because they are rather
+	// uncommon, we do it simply by testing whether the king is attacked after
+	// the move is made.
+	if m.Type() == EnPassant {
+		ksq := p.KingSquare(us)
+		capsq := to - Square(PawnPush(us))
+		occupied := (p.PiecesByType(AllPieces) ^ from.Bitboard() ^ capsq.Bitboard()) | to.Bitboard()
+
+		return AttacksBB(Rook, ksq, occupied)&(p.Pieces(1-us, Queen)|p.Pieces(1-us, Rook)) == 0 &&
+			AttacksBB(Bishop, ksq, occupied)&(p.Pieces(1-us, Queen)|p.Pieces(1-us, Bishop)) == 0
+	}
+
+	// Castling moves generation does not check if the castling path is clear
+	// of enemy attacks, it is delayed at a later time: now!
+	if m.Type() == Castling {
+		// After castling, the rook and king final positions are the same in
+		// Chess960 as they would be in standard chess.
+		step := East
+		to = SquareC1.RelativeSquare(us)
+		if m.ToSquare() > from {
+			step = West
+			to = SquareG1.RelativeSquare(us)
+		}
+
+		for s := to; s != from; s += Square(step) {
+			if p.AttackersTo(s)&p.PiecesByColor(1-us) != 0 {
+				return false
+			}
+		}
+
+		// In case of Chess960, verify if the Rook blocks some checks
+		return !p.chess960 || p.KingBlockers(us)&m.ToSquare().Bitboard() == 0
+	}
+
+	// If the moving piece is a king, check whether the destination square is
+	// attacked by the opponent.
+	if p.PieceOn(from).Type() == King {
+		return p.attackersTo(to, p.PiecesByType(AllPieces)^from.Bitboard())&p.PiecesByColor(1-us) == 0
+	}
+
+	// A non-king move is legal if and only if it is not pinned or it is
+	// moving along the ray towards or away from the king.
+	return p.KingBlockers(us)&from.Bitboard() == 0 || Aligned(from, to, p.KingSquare(us))
+}
+
+// IsMovePseudoLegal() tests whether a random move is pseudo-legal. It is used
+// to validate moves from the transposition table and the killer moves, that
+// may come from a different position.
+func (p *Position) IsMovePseudoLegal(m Move) bool {
+	us := p.sideToMove
+	from := m.FromSquare()
+	to := m.ToSquare()
+	pc := p.MovedPiece(m)
+
+	// Use a slower but simpler function for uncommon cases
+	if m.Type() != Normal {
+		var ml MoveList
+		if p.Checkers() != 0 {
+			p.Generate(Evasions, &ml)
+		} else {
+			p.Generate(NonEvasions, &ml)
+		}
+		return ml.Contains(m)
+	}
+
+	// It is not a promotion, so the promotion piece must be empty
+	if m.PromotionType() != Knight {
+		return false
+	}
+
+	// If the from square is not occupied by a piece belonging to the side to
+	// move, the move is obviously not legal.
+	if pc == NoPiece || pc.Color() != us {
+		return false
+	}
+
+	// The destination square cannot be occupied by a friendly piece
+	if p.PiecesByColor(us)&to.Bitboard() != 0 {
+		return false
+	}
+
+	// Handle the special case of a pawn move
+	if pc.Type() == Pawn {
+		// We have already handled promotion moves, so destination cannot be
+		// on the 8th/1st rank.
+		if (Rank8BB|Rank1BB)&to.Bitboard() != 0 {
+			return false
+		}
+
+		push := Square(PawnPush(us))
+		if PawnAttacks[us][from]&p.PiecesByColor(1-us)&to.Bitboard() == 0 && // Not a capture
+			!(from+push == to && p.PieceOn(to) == NoPiece) && // Not a single push
+			!(from+2*push == to && // Not a double push
+				from.RelativeRank(us) == Rank2 &&
+				p.PieceOn(to) == NoPiece &&
+				p.PieceOn(to-push) == NoPiece) {
+			return false
+		}
+	} else if AttacksBB(pc.Type(), from, p.PiecesByType(AllPieces))&to.Bitboard() == 0 {
+		return false
+	}
+
+	// Evasions generator already takes care to avoid some kind of illegal
+	// moves and legal() relies on this. We therefore have to take care that
+	// the same kind of moves are filtered out here.
+	if p.Checkers() != 0 {
+		if pc.Type() != King {
+			// Double check? In this case, a king move is required
+			if p.Checkers().MoreThanOne() {
+				return false
+			}
+
+			// Our move must be a blocking interposition or a capture of the
+			// checking piece.
+			if BetweenBB[p.KingSquare(us)][p.Checkers().lsb()]&to.Bitboard() == 0 {
+				return false
+			}
+		} else if p.attackersTo(to, p.PiecesByType(AllPieces)^from.Bitboard())&p.PiecesByColor(1-us) != 0 {
+			// In case of king moves under check we have to remove the king so
+			// as to catch invalid moves like b1a1 when opposite queen is on c1.
+			return false
+		}
+	}
+
+	return true
 }
 
-func (p Position) IsMovePseudoLegal(m Move) bool {
-	// TODO: implement
-	return false
+func (p *Position) IsMoveCapture(m Move) bool {
+	return (p.PieceOn(m.ToSquare()) != NoPiece && m.Type() != Castling) || m.Type() == EnPassant
 }
 
-func (p Position) IsMoveCapture(m Move) bool {
-	// TODO: implement
-	return false
+// GivesCheck() tests whether a pseudo-legal move gives check.
+func (p *Position) GivesCheck(m Move) bool {
+	us := p.sideToMove
+	from := m.FromSquare()
+	to := m.ToSquare()
+	ksq := p.KingSquare(1 - us)
+
+	// Is there a direct check?
+	if p.CheckSquares(p.PieceOn(from).Type())&to.Bitboard() != 0 {
+		return true
+	}
+
+	// Is there a discovered check?
+	if p.KingBlockers(1-us)&from.Bitboard() != 0 {
+		return !Aligned(from, to, ksq) || m.Type() == Castling
+	}
+
+	switch m.Type() {
+	case Promotion:
+		return AttacksBB(m.PromotionType(), to, p.PiecesByType(AllPieces)^from.Bitboard())&ksq.Bitboard() != 0
+
+	// En passant captures are a special case of discovered check, the
+	// captured pawn may also be the blocker.
+	case EnPassant:
+		capsq := NewSquare(to.File(), from.Rank())
+		b := (p.PiecesByType(AllPieces) ^ from.Bitboard() ^ capsq.Bitboard()) | to.Bitboard()
+		return (AttacksBB(Rook, ksq, b)&(p.Pieces(us, Queen)|p.Pieces(us, Rook)))|
+			(AttacksBB(Bishop, ksq, b)&(p.Pieces(us, Queen)|p.Pieces(us, Bishop))) != 0
+
+	// Castling can only give a check with the rook
+	case Castling:
+		_, rto, _ := castlingSquares(us, from, to)
+		return p.CheckSquares(Rook)&rto.Bitboard() != 0
+
+	default:
+		return false
+	}
 }
 
-func (p Position) GivesCheck(m Move) bool {
-	// TODO: implement
-	return false
+func (p *Position) MovedPiece(m Move) Piece {
+	return p.PieceOn(m.FromSquare())
 }
 
-func (p Position) MovedPiece(m Move) Piece {
-	// TODO: implement
-	return 0
+func (p *Position) CapturedPiece(m Move) Piece {
+	switch m.Type() {
+	case Castling:
+		return NoPiece
+	case EnPassant:
+		return NewPiece(1-p.sideToMove, Pawn)
+	default:
+		return p.PieceOn(m.ToSquare())
+	}
 }
 
-func (p Position) CapturedPiece(m Move) Piece {
-	// TODO: implement
-	return 0
+// LastCapturedPiece() returns the piece captured by the move that led to the
+// position, if any.
+func (p *Position) LastCapturedPiece() Piece {
+	return p.state.capturedPiece
 }
 
 // Doing and undoing moves
@@ -241,6 +858,8 @@ func (p *Position) DoMove(m Move) {
 }
 
 func (p *Position) doMove(m Move, givesCheck bool) {
+	k := p.state.key ^ zobristSide
+
 	newSt := p.state.Copy()
 	newSt.prevState = p.state
 	p.state = newSt
@@ -254,39 +873,45 @@ func (p *Position) doMove(m Move, givesCheck bool) {
 	from := m.FromSquare()
 	to := m.ToSquare()
 	pc := p.PieceOn(from)
-	captured := p.PieceOn(to)
-	if m.Type() == EnPassant {
-		captured = NewPiece(them, Pawn)
-	}
+	captured := p.CapturedPiece(m)
 
 	if m.Type() == Castling {
-		p.doCastling(us, from, to)
-		captured = NoPiece
+		rook := NewPiece(us, Rook)
+		rfrom, rto := p.doCastling(us, from, to)
+		k ^= zobristPsq[rook][rfrom] ^ zobristPsq[rook][rto]
 	}
 
 	if captured != NoPiece {
 		capsq := to
-
-		if captured.Type() == Pawn {
-			if m.Type() == EnPassant {
-				capsq -= Square(PawnPush(us))
-			}
-		}
-
-		p.RemovePiece(capsq)
-
 		if m.Type() == EnPassant {
-			p.board[capsq] = NoPiece
+			capsq -= Square(PawnPush(us))
 		}
 
+		p.RemovePiece(capsq)
+		k ^= zobristPsq[captured][capsq]
 		p.state.rule50 = 0
 	}
 
-	p.state.epSquare = SquareNone
+	// Update hash key
+	if m.Type() == Castling {
+		_, _, kto := castlingSquares(us, from, to)
+		k ^= zobristPsq[pc][from] ^ zobristPsq[pc][kto]
+	} else {
+		k ^= zobristPsq[pc][from] ^ zobristPsq[pc][to]
+	}
+
+	// Reset en passant square
+	if p.state.epSquare != SquareNone {
+		k ^= zobristEnpassant[p.state.epSquare.File()]
+		p.state.epSquare = SquareNone
+	}
 
+	// Update castling rights if needed
 	if p.state.castlingRights != 0 {
 		if p.castlingRightsMask[from] != 0 || p.castlingRightsMask[to] != 0 {
+			k ^= zobristCastling[p.state.castlingRights]
 			p.state.castlingRights &= ^(p.castlingRightsMask[from] | p.castlingRightsMask[to])
+			k ^= zobristCastling[p.state.castlingRights]
 		}
 	}
 
@@ -295,39 +920,224 @@ func (p *Position) doMove(m Move, givesCheck bool) {
 	}
 
 	if pc.Type() == Pawn {
-		if int(to)^int(from) == 16 && (PawnAttacksBB(us, Square(to-Square(PawnPush(us))).Bitboard())&p.Pieces(them, Pawn) != 0) {
+		// Set en passant square if the moved pawn can be captured
+		if int(to)^int(from) == 16 && (PawnAttacksBB(us, (to-Square(PawnPush(us))).Bitboard())&p.Pieces(them, Pawn) != 0) {
 			p.state.epSquare = to - Square(PawnPush(us))
+			k ^= zobristEnpassant[p.state.epSquare.File()]
 		} else if m.Type() == Promotion {
 			promPc := NewPiece(us, m.PromotionType())
 			p.RemovePiece(to)
 			p.PutPiece(promPc, to)
+			k ^= zobristPsq[pc][to] ^ zobristPsq[promPc][to]
 		}
 		p.state.rule50 = 0
 	}
 
 	p.state.capturedPiece = captured
+	p.state.key = k
 
 	p.state.checkersBB = 0
 	if givesCheck {
-		p.state.checkersBB = p.AttackersTo(Square(p.Pieces(them, King).lsb()))
+		p.state.checkersBB = p.AttackersTo(p.KingSquare(them)) & p.PiecesByColor(us)
+	}
+
+	p.sideToMove = them
+
+	p.setCheckInfo()
+
+	// Calculate the repetition info. It is the ply distance from the previous
+	// occurrence of the same position, negative in the 3-fold case, or zero
+	// if the position was not repeated.
+	p.state.repetition = 0
+	end := p.state.rule50
+	if p.state.pliesFromNull < end {
+		end = p.state.pliesFromNull
+	}
+	if end >= 4 {
+		stp := p.state.prevState.prevState
+		for i := 4; i <= end; i += 2 {
+			stp = stp.prevState.prevState
+			if stp.key == p.state.key {
+				if stp.repetition != 0 {
+					p.state.repetition = -i
+				} else {
+					p.state.repetition = i
+				}
+				break
+			}
+		}
+	}
+
+	if debugChecks {
+		if err := p.validate(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// UndoMove() unmakes a move. When it returns, the position should be
+// restored to exactly the same state as before the move was made.
+func (p *Position) UndoMove(m Move) {
+	p.sideToMove = 1 - p.sideToMove
+
+	us := p.sideToMove
+	from := m.FromSquare()
+	to := m.ToSquare()
+
+	if m.Type() == Promotion {
+		p.RemovePiece(to)
+		p.PutPiece(NewPiece(us, Pawn), to)
+	}
+
+	if m.Type() == Castling {
+		p.undoCastling(us, from, to)
+	} else {
+		p.movePiece(to, from) // Put the piece back at the source square
+
+		if p.state.capturedPiece != NoPiece {
+			capsq := to
+			if m.Type() == EnPassant {
+				capsq -= Square(PawnPush(us))
+			}
+
+			p.PutPiece(p.state.capturedPiece, capsq) // Restore the captured piece
+		}
+	}
+
+	// Finally point our state pointer back to the previous state
+	p.state = p.state.prevState
+	p.gamePly--
+}
+
+// DoNullMove() is used to do a "null move": it flips the side to move
+// without executing any move on the board.
+func (p *Position) DoNullMove() {
+	newSt := *p.state
+	newSt.prevState = p.state
+	p.state = &newSt
+
+	if p.state.epSquare != SquareNone {
+		p.state.key ^= zobristEnpassant[p.state.epSquare.File()]
+		p.state.epSquare = SquareNone
+	}
+
+	p.state.key ^= zobristSide
+	p.state.rule50++
+	p.state.pliesFromNull = 0
+
+	p.sideToMove = 1 - p.sideToMove
+
+	p.setCheckInfo()
+
+	p.state.repetition = 0
+
+	if debugChecks {
+		if err := p.validate(); err != nil {
+			panic(err)
+		}
 	}
+}
 
-	// TODO: implement
+func (p *Position) UndoNullMove() {
+	p.state = p.state.prevState
+	p.sideToMove = 1 - p.sideToMove
 }
 
-func (p *Position) UndooMove(m Move) {
-	// TODO: implement
+// IsDraw() tests whether the position is drawn by 50-move rule, by
+// repetition or by insufficient material. It does not detect stalemates. A
+// position that repeats once earlier but strictly after the root (ply plies
+// ago), or that repeats twice before or at the root, is considered a draw.
+func (p *Position) IsDraw(ply int) bool {
+	if p.state.rule50 > 99 && (p.Checkers() == 0 || p.hasLegalMoves()) {
+		return true
+	}
+
+	if p.state.repetition != 0 && p.state.repetition < ply {
+		return true
+	}
+
+	return p.IsInsufficientMaterial()
 }
 
-func (p *Position) DoNullMove(m Move) {
-	// TODO: implement
+// IsInsufficientMaterial() tests whether neither side can possibly checkmate,
+// that is only kings and at most one minor piece or only bishops on squares
+// of the same color are left.
+func (p *Position) IsInsufficientMaterial() bool {
+	if p.PiecesByType(Pawn)|p.PiecesByType(Rook)|p.PiecesByType(Queen) != 0 {
+		return false
+	}
+
+	minors := p.PiecesByType(Knight) | p.PiecesByType(Bishop)
+	if !minors.MoreThanOne() {
+		return true
+	}
+
+	bishops := p.PiecesByType(Bishop)
+	return p.PiecesByType(Knight) == 0 && (bishops&DarkSquares == 0 || bishops & ^DarkSquares == 0)
 }
 
-func (p *Position) UndoNullMove(m Move) {
-	// TODO: implement
+func (p *Position) hasLegalMoves() bool {
+	var ml MoveList
+	p.Generate(Legal, &ml)
+	return ml.Len() != 0
+}
+
+type GameResult int
+
+const (
+	Ongoing GameResult = iota
+	Checkmate
+	Stalemate
+	DrawRepetition
+	DrawFiftyMoves
+	DrawInsufficientMaterial
+)
+
+func (r GameResult) String() string {
+	switch r {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case DrawRepetition:
+		return "threefold repetition"
+	case DrawFiftyMoves:
+		return "fifty-move rule"
+	case DrawInsufficientMaterial:
+		return "insufficient material"
+	default:
+		return "ongoing"
+	}
+}
+
+// GameResult() classifies the position according to the rules of the game,
+// as needed to adjudicate a game. Unlike IsDraw() a repetition is only a
+// draw on its third occurrence. Checkmate takes precedence over the
+// fifty-move rule.
+func (p *Position) GameResult() GameResult {
+	if !p.hasLegalMoves() {
+		if p.Checkers() != 0 {
+			return Checkmate
+		}
+		return Stalemate
+	}
+
+	if p.state.rule50 > 99 {
+		return DrawFiftyMoves
+	}
+
+	if p.state.repetition < 0 {
+		return DrawRepetition
+	}
+
+	if p.IsInsufficientMaterial() {
+		return DrawInsufficientMaterial
+	}
+
+	return Ongoing
 }
 
-func (p Position) String() string {
+func (p *Position) String() string {
 	s := "  +---+---+---+---+---+---+---+---+\n"
 	for sq := SquareA8; sq.IsOK(); sq += -16 {
 		s += fmt.Sprintf("%v ", sq.Rank())
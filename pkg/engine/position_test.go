@@ -0,0 +1,398 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFenRoundTrip(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"rnbqkbnr/pppp1ppp/8/8/3Pp3/5P2/PPP1P1PP/RNBQKBNR b KQkq d3 0 3",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		"4k3/8/8/8/8/8/8/4K2R w K - 99 150",
+		"8/8/8/8/8/8/6k1/4K3 b - - 12 61",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+	}
+	for _, fen := range fens {
+		pos, err := NewPosition(fen)
+		if err != nil {
+			t.Errorf("NewPosition(%q): %v", fen, err)
+			continue
+		}
+		if got := pos.Fen(); got != fen {
+			t.Errorf("NewPosition(%q).Fen() = %q", fen, got)
+		}
+	}
+}
+
+func TestNewPositionInvalid(t *testing.T) {
+	fens := []string{
+		// missing or extra fields
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 1",
+		// bad piece placement
+		"rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1",
+		// two kings, or none
+		"4k3/8/8/8/8/8/8/3KK3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/8 w - - 0 1",
+		// pawns on the first or last rank
+		"P3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/p3K3 w - - 0 1",
+		// bad active color
+		"4k3/8/8/8/8/8/8/4K3 x - - 0 1",
+		// bad castling rights
+		"4k3/8/8/8/8/8/8/4K3 w K - 0 1",
+		"4k3/8/8/8/8/8/8/R3K3 w K - 0 1",
+		"r3k3/8/8/8/8/8/8/R3K3 w Qkq - 0 1",
+		"4k3/8/8/8/8/8/8/3RK2R w Kx - 0 1",
+		"4k3/8/8/8/8/8/4K3/R6R w KQ - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w KC - 0 1",
+		// bad en passant square
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d5 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d3 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - i6 0 1",
+		"4k3/8/8/3pP3/8/8/8/4K3 w - d 0 1",
+		// bad halfmove clock or fullmove number
+		"4k3/8/8/8/8/8/8/4K3 w - - -1 1",
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 x",
+		// the side not to move is in check
+		"4k3/4R3/8/8/8/8/8/4K3 w - - 0 1",
+		"r3k2r/p1ppqPb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	}
+	for _, fen := range fens {
+		if _, err := NewPosition(fen); err == nil {
+			t.Errorf("NewPosition(%q): expected an error", fen)
+		}
+	}
+}
+
+func TestDoUndoMove(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+	}
+
+	r := NewPRNG(1070372)
+	for _, fen := range fens {
+		pos, err := NewPosition(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for game := 0; game < 20; game++ {
+			// Undoing a move restores the position and its state as they
+			// were, down to the state pointer
+			type snapshot struct {
+				pos Position
+				st  State
+			}
+			var moves []Move
+			var prev []snapshot
+			for ply := 0; ply < 100; ply++ {
+				var ml MoveList
+				pos.Generate(Legal, &ml)
+				if ml.Len() == 0 {
+					break
+				}
+				m := ml.Move(int(r.Rand64() % uint64(ml.Len())))
+
+				moves = append(moves, m)
+				prev = append(prev, snapshot{*pos, *pos.state})
+				pos.DoMove(m)
+				if err := pos.validate(); err != nil {
+					t.Fatalf("%v after %v", err, m)
+				}
+			}
+
+			for i := len(moves) - 1; i >= 0; i-- {
+				m := moves[i]
+				pos.UndoMove(m)
+				if err := pos.validate(); err != nil {
+					t.Fatalf("%v after undoing %v", err, m)
+				}
+				if *pos != prev[i].pos || *pos.state != prev[i].st {
+					t.Fatalf("undoing %v: got %v, want %v", m, pos.Fen(), prev[i].pos.Fen())
+				}
+			}
+		}
+	}
+}
+
+func TestPawnAndMaterialKeys(t *testing.T) {
+	fens := []string{
+		StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	}
+
+	check := func(pos *Position, after string) {
+		t.Helper()
+		if got, want := pos.PawnKey(), pos.computePawnKey(); got != want {
+			t.Fatalf("%v: pawn key %x after %v, want %x", pos.Fen(), got, after, want)
+		}
+		if got, want := pos.MaterialKey(), pos.computeMaterialKey(); got != want {
+			t.Fatalf("%v: material key %x after %v, want %x", pos.Fen(), got, after, want)
+		}
+	}
+
+	r := NewPRNG(20221018)
+	for _, fen := range fens {
+		pos, err := NewPosition(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(pos, "the FEN")
+
+		for game := 0; game < 20; game++ {
+			var moves []Move
+			for ply := 0; ply < 100; ply++ {
+				var ml MoveList
+				pos.Generate(Legal, &ml)
+				if ml.Len() == 0 {
+					break
+				}
+				m := ml.Move(int(r.Rand64() % uint64(ml.Len())))
+
+				pawnKey, materialKey := pos.PawnKey(), pos.MaterialKey()
+				pt := pos.MovedPiece(m).Type()
+				capture := pos.PieceOn(m.ToSquare()) != NoPiece && m.Type() != Castling
+
+				moves = append(moves, m)
+				pos.DoMove(m)
+				check(pos, m.String())
+
+				// Only the pawns and the kings change the pawn key, only
+				// captures and promotions the material key
+				if pt != Pawn && pt != King && !capture && pos.PawnKey() != pawnKey {
+					t.Fatalf("%v: %v changed the pawn key", pos.Fen(), m)
+				}
+				if !capture && m.Type() == Normal && pos.MaterialKey() != materialKey {
+					t.Fatalf("%v: %v changed the material key", pos.Fen(), m)
+				}
+			}
+
+			for i := len(moves) - 1; i >= 0; i-- {
+				pos.UndoMove(moves[i])
+				check(pos, "undoing "+moves[i].String())
+			}
+		}
+	}
+
+	// The material key does not depend on where the pieces are
+	a, _ := NewPosition("4k3/8/2n5/8/8/5B2/8/1R2K3 w - - 0 1")
+	b, _ := NewPosition("1n2k3/8/8/8/8/8/R7/4K2B b - - 0 1")
+	if a.MaterialKey() != b.MaterialKey() || a.PawnKey() != b.PawnKey() {
+		t.Error("the same material has different keys")
+	}
+}
+
+// playUCI() plays the moves in coordinate notation, separated by spaces, "0"
+// being a null move.
+func playUCI(t *testing.T, pos *Position, moves string) {
+	t.Helper()
+	for _, s := range strings.Fields(moves) {
+		if s == "0" {
+			pos.DoNullMove()
+			continue
+		}
+		var ml MoveList
+		pos.Generate(Legal, &ml)
+		m := MoveNone
+		for i := 0; i < ml.Len(); i++ {
+			if ml.Move(i).String() == s {
+				m = ml.Move(i)
+			}
+		}
+		if m == MoveNone {
+			t.Fatalf("illegal move %v in %v", s, pos.Fen())
+		}
+		pos.DoMove(m)
+	}
+}
+
+func TestDraws(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		moves  string
+		ply    int // the plies since the root, for IsDraw()
+		draw   bool
+		result GameResult
+	}{
+		{"repeated once", StartFEN, "g1f3 g8f6 f3g1 f6g8", 0, false, Ongoing},
+		{"repeated once after the root", StartFEN, "g1f3 g8f6 f3g1 f6g8", 5, true, Ongoing},
+		{"threefold repetition", StartFEN, "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", 0, true, DrawRepetition},
+		{"repetition across a null move", StartFEN, "g1f3 0 f3g1 0 g1f3 0 f3g1 0", 9, false, Ongoing},
+		{"repetition after a null move", StartFEN, "b1c3 0 g1f3 g8f6 f3g1 f6g8", 7, true, Ongoing},
+		{"fifty-move rule", "7k/8/6K1/8/8/8/8/R7 w - - 99 80", "a1a7", 1, true, DrawFiftyMoves},
+		{"fifty-move rule reset by a capture", "7k/8/6K1/8/8/8/r7/R7 w - - 99 80", "a1a2", 1, false, Ongoing},
+		{"mate on the hundredth ply", "7k/8/6K1/8/8/8/8/R7 w - - 99 80", "a1a8", 1, false, Checkmate},
+		{"stalemate", "k7/8/8/1Q6/8/8/8/7K w - - 0 1", "b5b6", 1, false, Stalemate},
+		{"KK", "8/8/4k3/8/8/4K3/8/8 w - - 0 1", "", 0, true, DrawInsufficientMaterial},
+		{"KNK", "8/8/4k3/8/8/4K3/8/6N1 w - - 0 1", "", 0, true, DrawInsufficientMaterial},
+		{"KBK", "8/8/4k3/8/8/4K3/8/5B2 b - - 0 1", "", 0, true, DrawInsufficientMaterial},
+		{"KB vs KB on the same color", "8/8/4k3/3b4/8/4K3/8/5B2 w - - 0 1", "", 0, true, DrawInsufficientMaterial},
+		{"KBB on the same color", "8/8/4k3/8/8/4K3/6B1/5B2 w - - 0 1", "", 0, true, DrawInsufficientMaterial},
+		{"KB vs KB on different colors", "8/8/4k3/4b3/8/4K3/8/5B2 w - - 0 1", "", 0, false, Ongoing},
+		{"KNN", "8/8/4k3/8/8/4K3/8/5NN1 w - - 0 1", "", 0, false, Ongoing},
+		{"KN vs KB", "8/8/4k3/4b3/8/4K3/8/6N1 w - - 0 1", "", 0, false, Ongoing},
+		{"KPK", "8/8/4k3/8/8/4K3/4P3/8 w - - 0 1", "", 0, false, Ongoing},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		playUCI(t, pos, tt.moves)
+		if got := pos.IsDraw(tt.ply); got != tt.draw {
+			t.Errorf("%s: IsDraw(%d) = %v, want %v", tt.name, tt.ply, got, tt.draw)
+		}
+		if got := pos.GameResult(); got != tt.result {
+			t.Errorf("%s: GameResult() = %v, want %v", tt.name, got, tt.result)
+		}
+	}
+}
+
+func TestNewUCIMove(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		typ  MoveType
+		ok   bool
+	}{
+		{"quiet move", StartFEN, "e2e4", Normal, true},
+		{"upper case", StartFEN, "G1F3", Normal, true},
+		{"illegal move", StartFEN, "e2e5", Normal, false},
+		{"move of the other side", StartFEN, "e7e5", Normal, false},
+		{"pinned piece", "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", "e2c3", Normal, false},
+		{"empty", StartFEN, "", Normal, false},
+		{"too short", StartFEN, "e2", Normal, false},
+		{"too long", StartFEN, "e2e4e", Normal, false},
+		{"invalid square", StartFEN, "i2i4", Normal, false},
+		{"null move", StartFEN, "0000", Normal, false},
+		{"castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", Castling, true},
+		{"long castling", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", Castling, true},
+		{"castling as king captures rook", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1h1", Normal, false},
+		{"castling without the right", "r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1", "e1g1", Normal, false},
+		{"chess960 castling", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", "e1h1", Castling, true},
+		{"chess960 long castling", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", "e1a1", Castling, true},
+		{"chess960 castling as king two squares", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", "e1g1", Normal, false},
+		{"chess960 castling onto the rook", "r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", "e1g1", Castling, true},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", EnPassant, true},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", Promotion, true},
+		{"underpromotion", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8n", Promotion, true},
+		{"promotion without a piece", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8", Normal, false},
+		{"promotion to a king", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8k", Normal, false},
+		{"promotion of a piece", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "e1e2q", Normal, false},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := pos.NewUCIMove(tt.move)
+		if !tt.ok {
+			if m != MoveNone {
+				t.Errorf("%s: NewUCIMove(%q) = %v, want MoveNone", tt.name, tt.move, m)
+			}
+			continue
+		}
+		if m == MoveNone || m.Type() != tt.typ || m.UCIString(pos.IsChess960()) != strings.ToLower(tt.move) {
+			t.Errorf("%s: NewUCIMove(%q) = %v of type %v", tt.name, tt.move, m, m.Type())
+		}
+	}
+
+	// The promotion piece is the one given
+	pos, _ := NewPosition("4k3/1P6/8/8/8/8/8/4K3 w - - 0 1")
+	for i, c := range "nbrq" {
+		if m := pos.NewUCIMove("b7b8" + string(c)); m.PromotionType() != Knight+PieceType(i) {
+			t.Errorf("b7b8%c: promotion to %v", c, m.PromotionType())
+		}
+	}
+}
+
+func TestGivesCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		move  string
+		check bool
+	}{
+		{"quiet move", StartFEN, "e2e4", false},
+		{"direct check", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", true},
+		{"discovered check", "4k3/8/8/8/8/8/4N3/4RK2 w - - 0 1", "e2c3", true},
+		{"castling check", "5k2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", true},
+		{"long castling check", "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1", true},
+		{"chess960 castling check", "5k2/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", "e1g1", true},
+		{"en passant check", "8/8/8/2k5/3Pp3/8/4K3/8 b - d3 0 1", "e4d3", true},
+		{"en passant discovered check", "8/8/8/8/K2Pp2r/8/8/4k3 b - d3 0 1", "e4d3", true},
+		{"promotion check", "3k4/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", true},
+		{"knight promotion check", "8/1P1k4/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", true},
+		{"queen promotion without check", "8/1P1k4/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", false},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := pos.NewUCIMove(tt.move)
+		if m == MoveNone {
+			t.Errorf("%s: illegal move %v", tt.name, tt.move)
+			continue
+		}
+		if got := pos.GivesCheck(m); got != tt.check {
+			t.Errorf("%s: GivesCheck(%v) = %v, want %v", tt.name, tt.move, got, tt.check)
+		}
+	}
+
+	// Every move of the perft trees gives check if the king is attacked
+	// after it
+	var walk func(pos *Position, depth int)
+	walk = func(pos *Position, depth int) {
+		var ml MoveList
+		pos.Generate(Legal, &ml)
+		for i := 0; i < ml.Len(); i++ {
+			m := ml.Move(i)
+			givesCheck := pos.GivesCheck(m)
+			pos.doMove(m, true)
+			if check := pos.Checkers() != 0; givesCheck != check {
+				pos.UndoMove(m)
+				t.Fatalf("%v: GivesCheck(%v) = %v, want %v", pos.Fen(), m.UCIString(pos.IsChess960()), givesCheck, check)
+			}
+			if depth > 1 {
+				walk(pos, depth-1)
+			}
+			pos.UndoMove(m)
+		}
+	}
+	for _, tt := range perftTests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walk(pos, 3)
+	}
+}
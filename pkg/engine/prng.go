@@ -0,0 +1,28 @@
+package engine
+
+// PRNG is a xorshift64star pseudo-random number generator. It is used to
+// find the magic numbers and the zobrist keys in a deterministic way, so the
+// same seed always yields the same tables.
+type PRNG struct {
+	s uint64
+}
+
+func NewPRNG(seed uint64) *PRNG {
+	if seed == 0 {
+		panic("PRNG seed must not be zero")
+	}
+	return &PRNG{s: seed}
+}
+
+func (r *PRNG) Rand64() uint64 {
+	r.s ^= r.s >> 12
+	r.s ^= r.s << 25
+	r.s ^= r.s >> 27
+	return r.s * 2685821657736338717
+}
+
+// SparseRand() returns a random number with only 1/8th of its bits set on
+// average, which makes for good magic candidates.
+func (r *PRNG) SparseRand() uint64 {
+	return r.Rand64() & r.Rand64() & r.Rand64()
+}
@@ -0,0 +1,70 @@
+package engine
+
+import "math"
+
+// Selectivity enables the techniques that prune or reduce moves the search
+// considers unpromising. They are sound on average but not always, so each
+// one can be turned off to test its effect.
+type Selectivity struct {
+	NullMove        bool
+	LMR             bool
+	ReverseFutility bool
+	Futility        bool
+	Razoring        bool
+	LateMovePruning bool
+	SEEPruning      bool
+}
+
+// DefaultSelectivity() returns the selectivity with all the techniques
+// enabled.
+func DefaultSelectivity() Selectivity {
+	return Selectivity{
+		NullMove:        true,
+		LMR:             true,
+		ReverseFutility: true,
+		Futility:        true,
+		Razoring:        true,
+		LateMovePruning: true,
+		SEEPruning:      true,
+	}
+}
+
+// reductions[i] is used for the late move reductions, a move is reduced by
+// about reductions[depth] * reductions[moveCount] / 1024 plies.
+var reductions [MaxPly + 1]int
+
+func init() {
+	for i := 1; i < len(reductions); i++ {
+		reductions[i] = int(21.9 * math.Log(float64(i)))
+	}
+}
+
+// reduction() returns the depth reduction of a late move.
+func reduction(improving bool, depth, moveCount int) int {
+	if moveCount > MaxPly {
+		moveCount = MaxPly
+	}
+	r := reductions[depth] * reductions[moveCount]
+	if !improving && r > 1024 {
+		return (r+512)/1024 + 1
+	}
+	return (r + 512) / 1024
+}
+
+// futilityMargin() is how much the static evaluation can be expected to
+// change in depth plies.
+func futilityMargin(depth int, improving bool) Value {
+	if improving {
+		depth--
+	}
+	return Value(70 * depth)
+}
+
+// lateMoveCount() is the number of quiet moves searched before the rest are
+// pruned.
+func lateMoveCount(depth int, improving bool) int {
+	if improving {
+		return 3 + depth*depth
+	}
+	return (3 + depth*depth) / 2
+}
@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+const pieceTypeToChar = "  NBRQK"
+
+// MoveToSAN() converts a legal move to Standard Algebraic Notation (Nf3,
+// exd5, e8=Q+, O-O-O#).
+func (p *Position) MoveToSAN(m Move) string {
+	switch m {
+	case MoveNone:
+		return "(none)"
+	case MoveNull:
+		return "--"
+	}
+
+	from := m.FromSquare()
+	to := m.ToSquare()
+
+	var sb strings.Builder
+
+	if m.Type() == Castling {
+		if to > from {
+			sb.WriteString("O-O")
+		} else {
+			sb.WriteString("O-O-O")
+		}
+	} else {
+		pt := p.MovedPiece(m).Type()
+
+		if pt == Pawn {
+			if p.IsMoveCapture(m) {
+				sb.WriteByte(fileChar(from.File()))
+			}
+		} else {
+			sb.WriteByte(pieceTypeToChar[pt])
+
+			// A disambiguation occurs if we have more than one piece of type
+			// pt that can reach to with a legal move.
+			var ml MoveList
+			p.Generate(Legal, &ml)
+
+			var others Bitboard
+			for i := 0; i < ml.Len(); i++ {
+				n := ml.Move(i)
+				if n != m && n.ToSquare() == to && n.Type() != Castling && p.MovedPiece(n).Type() == pt {
+					others |= n.FromSquare().Bitboard()
+				}
+			}
+
+			if others != 0 {
+				switch {
+				case others&from.File().Bitboard() == 0:
+					sb.WriteByte(fileChar(from.File()))
+				case others&from.RankBB() == 0:
+					sb.WriteByte(rankChar(from.Rank()))
+				default:
+					sb.WriteString(squareString(from))
+				}
+			}
+		}
+
+		if p.IsMoveCapture(m) {
+			sb.WriteByte('x')
+		}
+
+		sb.WriteString(squareString(to))
+
+		if m.Type() == Promotion {
+			sb.WriteByte('=')
+			sb.WriteByte(pieceTypeToChar[m.PromotionType()])
+		}
+	}
+
+	p.DoMove(m)
+	if p.Checkers() != 0 {
+		if p.hasLegalMoves() {
+			sb.WriteByte('+')
+		} else {
+			sb.WriteByte('#')
+		}
+	}
+	p.UndoMove(m)
+
+	return sb.String()
+}
+
+// ParseSAN() converts a move in Standard Algebraic Notation to the
+// corresponding legal Move. The parser is lenient: it accepts castling
+// written with zeros (0-0) or without dashes (OO), the pawn letter (Pe4), a
+// missing capture sign, a missing '=' before the promotion piece, long
+// algebraic notation (Ng1-f3) and trailing check and annotation symbols (+,
+// #, !, ?). A pawn reaching the last rank without a promotion piece is
+// promoted to a queen.
+func (p *Position) ParseSAN(san string) (Move, error) {
+	str := strings.TrimRight(san, "+#!?")
+
+	var ml MoveList
+	p.Generate(Legal, &ml)
+
+	switch castling := strings.NewReplacer("0", "O", "-", "").Replace(strings.ToUpper(str)); castling {
+	case "OO", "OOO":
+		kingSide := len(castling) == 2
+		for i := 0; i < ml.Len(); i++ {
+			if m := ml.Move(i); m.Type() == Castling && (m.ToSquare() > m.FromSquare()) == kingSide {
+				return m, nil
+			}
+		}
+		return MoveNone, fmt.Errorf("invalid san %q: castling is not legal", san)
+	}
+
+	pt := Pawn
+	if len(str) > 0 && strings.IndexByte("NBRQK", str[0]) != -1 {
+		pt = PieceType(strings.IndexByte(pieceTypeToChar, str[0]))
+		str = str[1:]
+	} else if len(str) > 2 && str[0] == 'P' {
+		str = str[1:]
+	}
+
+	promotion := NoPieceType
+	if i := strings.IndexByte(str, '='); i != -1 {
+		if i != len(str)-2 {
+			return MoveNone, fmt.Errorf("invalid san %q: invalid promotion", san)
+		}
+		promotion = PieceType(strings.IndexByte(pieceTypeToChar, byte(strings.ToUpper(str[i+1:])[0])))
+		if promotion < Knight || promotion > Queen {
+			return MoveNone, fmt.Errorf("invalid san %q: invalid promotion piece", san)
+		}
+		str = str[:i]
+	} else if pt == Pawn && len(str) > 2 {
+		if pr := strings.IndexByte("nbrqNBRQ", str[len(str)-1]); pr != -1 {
+			promotion = Knight + PieceType(pr%4)
+			str = str[:len(str)-1]
+		}
+	}
+
+	str = strings.NewReplacer("x", "", "X", "", ":", "", "-", "").Replace(str)
+	if len(str) < 2 || len(str) > 4 {
+		return MoveNone, fmt.Errorf("invalid san %q", san)
+	}
+
+	to, err := ParseSquare(str[len(str)-2:])
+	if err != nil {
+		return MoveNone, fmt.Errorf("invalid san %q: %w", san, err)
+	}
+
+	fromFile, fromRank := File(-1), Rank(-1)
+	for _, c := range str[:len(str)-2] {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = File(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = Rank(c - '1')
+		default:
+			return MoveNone, fmt.Errorf("invalid san %q: invalid disambiguation", san)
+		}
+	}
+
+	match := MoveNone
+	for i := 0; i < ml.Len(); i++ {
+		m := ml.Move(i)
+		from := m.FromSquare()
+
+		if m.Type() == Castling || m.ToSquare() != to || p.MovedPiece(m).Type() != pt ||
+			(fromFile != -1 && from.File() != fromFile) || (fromRank != -1 && from.Rank() != fromRank) {
+			continue
+		}
+
+		if m.Type() == Promotion {
+			if (promotion == NoPieceType && m.PromotionType() != Queen) ||
+				(promotion != NoPieceType && m.PromotionType() != promotion) {
+				continue
+			}
+		} else if promotion != NoPieceType {
+			continue
+		}
+
+		if match != MoveNone {
+			return MoveNone, fmt.Errorf("invalid san %q: ambiguous move", san)
+		}
+		match = m
+	}
+
+	if match == MoveNone {
+		return MoveNone, fmt.Errorf("invalid san %q: illegal move in position %v", san, p.Fen())
+	}
+
+	return match, nil
+}
+
+func fileChar(f File) byte {
+	return byte('a' + f)
+}
+
+func rankChar(r Rank) byte {
+	return byte('1' + r)
+}
+
+func squareString(s Square) string {
+	return string([]byte{fileChar(s.File()), rankChar(s.Rank())})
+}
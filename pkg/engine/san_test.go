@@ -0,0 +1,95 @@
+package engine
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string // in coordinate notation
+		san  string
+	}{
+		{"pawn push", StartFEN, "e2e4", "e4"},
+		{"knight", StartFEN, "g1f3", "Nf3"},
+		{"disambiguation by file", "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"disambiguation by rank", "4k3/8/8/R7/8/8/4K3/R7 w - - 0 1", "a1a3", "R1a3"},
+		{"disambiguation by square", "4k3/8/8/8/8/Q7/4K3/Q1Q5 w - - 0 1", "a1b2", "Qa1b2"},
+		{"no disambiguation with a pinned piece", "4k3/8/8/8/1b6/8/3N4/4K1N1 w - - 0 1", "g1f3", "Nf3"},
+		{"pawn capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", "exd5"},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", "b8=N"},
+		{"promotion with check", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"capture promotion", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8r", "bxa8=R+"},
+		{"underpromotion capture", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8n", "bxa8=N"},
+		{"castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"long castling", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"castling with check", "5k2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", "O-O+"},
+		{"chess960 castling", "r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", "e1g1", "O-O"},
+		{"chess960 long castling", "r3k2r/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", "e1b1", "O-O-O"},
+		{"check", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8+"},
+		{"checkmate", "7k/8/6K1/8/8/8/8/R7 w - - 0 1", "a1a8", "Ra8#"},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := pos.NewUCIMove(tt.move)
+		if m == MoveNone {
+			t.Errorf("%s: illegal move %v", tt.name, tt.move)
+			continue
+		}
+		if got := pos.MoveToSAN(m); got != tt.san {
+			t.Errorf("%s: MoveToSAN(%v) = %v, want %v", tt.name, tt.move, got, tt.san)
+		}
+		if got, err := pos.ParseSAN(tt.san); err != nil || got != m {
+			t.Errorf("%s: ParseSAN(%v) = %v, %v, want %v", tt.name, tt.san, got.UCIString(pos.IsChess960()), err, tt.move)
+		}
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		move string // in coordinate notation, empty if san is invalid
+	}{
+		{StartFEN, "Pe4", "e2e4"},
+		{StartFEN, "e2-e4", "e2e4"},
+		{StartFEN, "Ng1-f3", "g1f3"},
+		{StartFEN, "Nf3!?", "g1f3"},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "ed5", "e4d5"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "OO", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "OOO", "e1c1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0+", "e1c1"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8", "b7b8q"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8N", "b7b8n"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=r", "b7b8r"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=K", ""},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "Rd1", ""},
+		{"4k3/8/8/8/8/8/8/4K2R w - - 0 1", "O-O", ""},
+		{StartFEN, "e5", ""},
+		{StartFEN, "Ke2", ""},
+		{StartFEN, "Nz3", ""},
+		{StartFEN, "", ""},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := pos.ParseSAN(tt.san)
+		if tt.move == "" {
+			if err == nil {
+				t.Errorf("%v: ParseSAN(%q) = %v, want an error", tt.fen, tt.san, m.UCIString(false))
+			}
+			continue
+		}
+		if err != nil || m != pos.NewUCIMove(tt.move) {
+			t.Errorf("%v: ParseSAN(%q) = %v, %v, want %v", tt.fen, tt.san, m.UCIString(false), err, tt.move)
+		}
+	}
+}
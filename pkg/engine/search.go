@@ -0,0 +1,819 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+// searcher holds the state of a search thread, an iterative deepening
+// principal variation search with a quiescence search at the leaves.
+type searcher struct {
+	id     int // the main thread is 0
+	pool   *threadPool
+	pos    *Position
+	limits uci.EngineSearchLimits
+	tt     *TranspositionTable
+	h      *history
+	sel    Selectivity
+	out    chan string
+
+	tm   *timeManager // shared by all the threads, only the main one uses it
+	stop *int32       // set when the search must stop
+
+	nodes     uint64 // updated atomically, the main thread reads it
+	selDepth  int
+	rootDepth int
+
+	// completedDepth is the depth of the last completed iteration, and
+	// bestValue its value
+	completedDepth int
+	bestValue      Value
+	// bestMoveChanges counts the changes of the best move at the root, it
+	// is halved at every iteration
+	bestMoveChanges float64
+
+	// The null move pruning is disabled for nmpColor before ply nmpMinPly,
+	// while a null move cutoff is verified
+	nmpMinPly int
+	nmpColor  Color
+
+	rootMoves []Move
+	// prevPV is the principal variation of the last completed iteration
+	prevPV []Move
+	pv     [MaxPly + 1][MaxPly + 1]Move
+	pvLen  [MaxPly + 1]int
+
+	stack [MaxPly + stackOffset + 3]stackEntry
+}
+
+// stackEntry holds the information of a ply of the current line.
+type stackEntry struct {
+	currentMove Move
+	// contHist is the continuation history of currentMove
+	contHist     *pieceToHistory
+	killers      [2]Move
+	staticEval   Value
+	excludedMove Move // the move skipped by the singular extension search
+	// extensions is the number of plies the current line was extended by
+	extensions int
+}
+
+// stackOffset is the number of entries before the root, so every ply can look
+// back at the moves 4 plies ago.
+const stackOffset = 4
+
+func newSearcher(pos *Position, limits uci.EngineSearchLimits, tm *timeManager, tt *TranspositionTable, h *history, sel Selectivity, stop *int32, out chan string) *searcher {
+	s := &searcher{pos: pos, limits: limits, tt: tt, h: h, sel: sel, out: out, tm: tm, stop: stop}
+
+	for i := range s.stack {
+		s.stack[i].contHist = &h.continuation[NoPiece][0]
+		s.stack[i].staticEval = ValueNone
+	}
+
+	var ml MoveList
+	pos.Generate(Legal, &ml)
+	for i := 0; i < ml.Len(); i++ {
+		m := ml.Move(i)
+		if len(limits.SearchMoves) == 0 || containsString(limits.SearchMoves, m.UCIString(pos.IsChess960())) {
+			s.rootMoves = append(s.rootMoves, m)
+		}
+	}
+
+	return s
+}
+
+// iterate() runs the iterative deepening loop, only the main thread reports
+// its progress.
+func (s *searcher) iterate() {
+	if len(s.rootMoves) == 0 {
+		if s.id == 0 {
+			s.out <- fmt.Sprintf("info depth 0 score %v\n", uciScore(s.rootValue()))
+		}
+		return
+	}
+
+	maxDepth := MaxPly - 1
+	if s.limits.Depth > 0 && s.limits.Depth < maxDepth {
+		maxDepth = s.limits.Depth
+	}
+	// A mate in Mate moves is found within 2*Mate plies, searching deeper
+	// would never end when there is none
+	if s.limits.Mate > 0 && 2*s.limits.Mate < maxDepth {
+		maxDepth = 2 * s.limits.Mate
+	}
+
+	for s.rootDepth = 1; s.rootDepth <= maxDepth; s.rootDepth++ {
+		if s.id != 0 && s.rootDepth > 1 {
+			i := (s.id - 1) % len(skipSize)
+			if (s.rootDepth+s.pos.GamePly()+skipPhase[i])/skipSize[i]%2 != 0 {
+				continue
+			}
+		}
+
+		s.selDepth = 0
+		v := s.search(-ValueInfinite, ValueInfinite, s.rootDepth, 0)
+
+		// The first iteration is always completed, so there is a legal move
+		// to return, the results of an interrupted iteration are discarded
+		if s.stopped() {
+			break
+		}
+
+		s.prevPV = append(s.prevPV[:0], s.pv[0][:s.pvLen[0]]...)
+		drop := s.bestValue - v
+		if s.completedDepth == 0 {
+			drop = 0
+		}
+		s.completedDepth, s.bestValue = s.rootDepth, v
+		if s.id == 0 {
+			s.info()
+		}
+
+		if s.limits.Mate > 0 && v >= MateIn(2*s.limits.Mate-1) {
+			break
+		}
+
+		// A mate shorter than the depth cannot be improved by searching
+		// deeper
+		if (v >= ValueMateInMaxPly && MateIn(s.rootDepth) < v) ||
+			(v <= ValueMatedInMaxPly && MatedIn(s.rootDepth) > v) {
+			break
+		}
+
+		if s.id == 0 && s.tm.timed && !s.limits.Infinite && s.tm.stopIteration(len(s.rootMoves) == 1, s.bestMoveChanges, drop) {
+			break
+		}
+		s.bestMoveChanges /= 2
+	}
+}
+
+// result() returns the best move and the expected reply, the second move of
+// the principal variation.
+func (s *searcher) result() (Move, Move) {
+	var best, ponder Move
+	if len(s.prevPV) > 0 {
+		best = s.prevPV[0]
+	}
+	if len(s.prevPV) > 1 {
+		ponder = s.prevPV[1]
+	}
+	return best, ponder
+}
+
+// ponderMoveFromTT() returns the TT move of the position after m, used as the
+// expected reply when the principal variation is too short.
+func ponderMoveFromTT(pos *Position, tt *TranspositionTable, m Move) Move {
+	pos.DoMove(m)
+	defer pos.UndoMove(m)
+
+	if tte, ok := tt.Probe(pos.Key()); ok && tte.Move != MoveNone &&
+		pos.IsMovePseudoLegal(tte.Move) && pos.IsMoveLegal(tte.Move) {
+		return tte.Move
+	}
+	return MoveNone
+}
+
+// rootValue() is the value of a position without legal moves.
+func (s *searcher) rootValue() Value {
+	if s.pos.Checkers() != 0 {
+		return MatedIn(0)
+	}
+	return ValueDraw
+}
+
+// info() reports the last completed iteration, with the nodes of all the
+// threads.
+func (s *searcher) info() {
+	elapsed := time.Since(s.tm.start).Milliseconds()
+	nodes := s.pool.nodes()
+
+	var pv []string
+	for _, m := range s.prevPV {
+		pv = append(pv, m.UCIString(s.pos.IsChess960()))
+	}
+
+	s.out <- fmt.Sprintf("info depth %v seldepth %v score %v nodes %v nps %v hashfull %v time %v pv %v\n",
+		s.completedDepth, s.selDepth, uciScore(s.bestValue), nodes, nodes*1000/uint64(elapsed+1), s.tt.Hashfull(), elapsed, strings.Join(pv, " "))
+}
+
+// uciScore() formats a value as "cp <x>" or "mate <y>", where y is in moves
+// and negative if the engine is getting mated.
+func uciScore(v Value) string {
+	switch {
+	case v >= ValueMateInMaxPly:
+		return fmt.Sprintf("mate %v", (ValueMate-v+1)/2)
+	case v <= ValueMatedInMaxPly:
+		return fmt.Sprintf("mate %v", -(ValueMate+v)/2)
+	default:
+		return fmt.Sprintf("cp %v", v)
+	}
+}
+
+// stopped() tests whether the search must stop, the first iteration is never
+// interrupted.
+func (s *searcher) stopped() bool {
+	return s.rootDepth > 1 && atomic.LoadInt32(s.stop) != 0
+}
+
+// checkLimits() stops the search when the nodes or the time are exhausted.
+// Every thread checks the nodes, so the limit is not overshot while the main
+// thread is between two checks, but only the main thread checks the time.
+func (s *searcher) checkLimits() {
+	// Summing the nodes of all the threads is slow, it is done periodically
+	if s.limits.Nodes > 0 && (len(s.pool.searchers) == 1 || s.nodes%64 == 0) && s.pool.nodes() >= uint64(s.limits.Nodes) {
+		atomic.StoreInt32(s.stop, 1)
+	}
+	if s.id == 0 && s.nodes%1024 == 0 && s.tm.timeLimited() && s.tm.elapsed() >= s.tm.maximum {
+		atomic.StoreInt32(s.stop, 1)
+	}
+}
+
+func (s *searcher) search(alpha, beta Value, depth, ply int) Value {
+	if depth <= 0 {
+		return s.qsearch(alpha, beta, depthQSChecks, ply)
+	}
+
+	pvNode := beta-alpha > 1
+
+	s.pvLen[ply] = ply
+	atomic.AddUint64(&s.nodes, 1)
+	s.checkLimits()
+	if ply > s.selDepth {
+		s.selDepth = ply
+	}
+
+	// The killers of the grandchildren are reset, so they only come from
+	// sibling nodes
+	s.ss(ply + 2).killers = [2]Move{}
+
+	if ply != 0 {
+		if s.stopped() {
+			return ValueZero
+		}
+		if s.pos.IsDraw(ply) {
+			return ValueDraw
+		}
+		if ply >= MaxPly {
+			return Evaluate(s.pos)
+		}
+
+		// Mate distance pruning, even if we mate at the next move our score
+		// would be at best MateIn(ply+1), and if we are mated now at most
+		// MatedIn(ply).
+		if alpha < MatedIn(ply) {
+			alpha = MatedIn(ply)
+		}
+		if beta > MateIn(ply+1) {
+			beta = MateIn(ply + 1)
+		}
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
+	// Transposition table lookup, the entry can cut off the search only at
+	// non PV nodes so the principal variation is not truncated.
+	tte, ttHit := s.tt.Probe(s.pos.Key())
+	ttValue, ttMove := ValueNone, MoveNone
+	if ttHit {
+		ttValue = valueFromTT(tte.Value, ply)
+		ttMove = tte.Move
+	}
+	if ply == 0 && len(s.prevPV) != 0 {
+		ttMove = s.prevPV[0]
+	}
+	excludedMove := s.ss(ply).excludedMove
+	if !pvNode && excludedMove == MoveNone && ttHit && tte.Depth >= depth && ttValue != ValueNone && tte.Bound&boundFor(ttValue >= beta) != 0 {
+		return ttValue
+	}
+
+	us := s.pos.SideToMove()
+	inCheck := s.pos.Checkers() != 0
+	eval := ValueNone
+	if !inCheck {
+		if ttHit && tte.Eval != ValueNone {
+			eval = tte.Eval
+		} else {
+			eval = Evaluate(s.pos)
+		}
+	}
+	s.ss(ply).staticEval = eval
+
+	// improving is set if the static evaluation is better than the one of
+	// our previous move, the pruning is then more cautious.
+	improving := false
+	if prev := s.ss(ply - 2).staticEval; eval != ValueNone && prev != ValueNone {
+		improving = eval > prev
+	}
+
+	// The node is not pruned when a mate score is in play, the static
+	// evaluation says nothing about it
+	if !pvNode && !inCheck && excludedMove == MoveNone && beta > ValueMatedInMaxPly && beta < ValueMateInMaxPly {
+		// Razoring, if the static evaluation is far below alpha check with
+		// the quiescence search whether any capture can bring it back.
+		if s.sel.Razoring && depth <= 2 && eval < alpha-400-200*Value(depth*depth) {
+			v := s.qsearch(alpha-1, alpha, depthQSChecks, ply)
+			if v < alpha {
+				return v
+			}
+		}
+
+		// Reverse futility pruning, if the static evaluation is well above
+		// beta it is unlikely to fall below it in the remaining depth. It is
+		// not trusted after a sacrifice, played for an attack that the
+		// static evaluation does not see.
+		if s.sel.ReverseFutility && depth < 9 && eval-futilityMargin(depth, improving) >= beta && eval < ValueMateInMaxPly &&
+			!s.isSacrifice(ply) {
+			return eval
+		}
+
+		// Null move pruning, if passing the move still fails high a real
+		// move most likely will too. It fails in zugzwang, where every move
+		// worsens the position, so it is not used without pieces and the
+		// cutoffs are verified in the endgame and at high depths.
+		if s.sel.NullMove && eval >= beta && s.ss(ply-1).currentMove != MoveNull &&
+			s.pos.NonPawnMaterial(us) != 0 && (ply >= s.nmpMinPly || us != s.nmpColor) {
+			r := 3 + depth/3
+			if d := int(eval-beta) / 200; d < 3 {
+				r += d
+			} else {
+				r += 3
+			}
+
+			ss := s.ss(ply)
+			ss.currentMove = MoveNull
+			ss.contHist = &s.h.continuation[NoPiece][0]
+			ss.extensions = s.ss(ply - 1).extensions
+			s.pos.DoNullMove()
+			v := -s.search(-beta, -beta+1, depth-r, ply+1)
+			s.pos.UndoNullMove()
+
+			if s.stopped() {
+				return ValueZero
+			}
+
+			if v >= beta {
+				// Do not return unproven mate scores
+				if v >= ValueMateInMaxPly {
+					v = beta
+				}
+				if s.nmpMinPly != 0 || (depth < 12 && s.pos.NonPawnMaterial(us) > PieceValue[Rook]) {
+					return v
+				}
+
+				// Verify the cutoff with a search of reduced depth, without
+				// null moves for us in the first plies.
+				s.nmpMinPly = ply + 3*(depth-r)/4
+				s.nmpColor = us
+				verified := s.search(beta-1, beta, depth-r, ply)
+				s.nmpMinPly = 0
+
+				if verified >= beta {
+					return v
+				}
+			}
+		}
+	}
+
+	counter := MoveNone
+	if prev := s.ss(ply - 1).currentMove; prev.IsOK() {
+		counter = s.h.counterMoves[s.pos.PieceOn(prev.ToSquare())][prev.ToSquare()]
+	}
+	mp := newMovePicker(s.pos, s.h, ttMove, s.ss(ply).killers, counter, s.contHist(ply))
+
+	oldAlpha := alpha
+	best, bestMove := -ValueInfinite, MoveNone
+	moveCount := 0
+	skipQuiets := false
+	var quietsSearched, capturesSearched []Move
+
+	for m := mp.next(skipQuiets); m != MoveNone; m = mp.next(skipQuiets) {
+		if ply == 0 && !containsMove(s.rootMoves, m) {
+			continue
+		}
+		if m == excludedMove || !s.pos.IsMoveLegal(m) {
+			continue
+		}
+		moveCount++
+		capture := s.pos.IsMoveCapture(m)
+		givesCheck := s.pos.GivesCheck(m)
+		newDepth := depth - 1
+
+		var r int
+		if s.sel.LMR {
+			r = reduction(improving, depth, moveCount)
+		}
+
+		// Prune the moves unlikely to matter, once we are not getting mated.
+		// Nothing is pruned in check or when a mate score is in play.
+		if ply != 0 && !inCheck && best > ValueMatedInMaxPly && alpha > ValueMatedInMaxPly && alpha < ValueMateInMaxPly &&
+			s.pos.NonPawnMaterial(us) != 0 {
+			// Late move pruning, skip the quiet moves after enough were
+			// searched
+			if s.sel.LateMovePruning && moveCount >= lateMoveCount(depth, improving) {
+				skipQuiets = true
+			}
+
+			// Checks are never pruned, they may be sacrifices leading to
+			// a mate
+			lmrDepth := newDepth - r
+			switch {
+			case givesCheck:
+			case capture:
+				// SEE pruning of the captures losing too much material
+				if s.sel.SEEPruning && !s.pos.SEE(m, Value(-100*depth)) {
+					continue
+				}
+			default:
+				// Futility pruning, a quiet move cannot raise the static
+				// evaluation above alpha
+				if s.sel.Futility && lmrDepth < 6 && eval+150+120*Value(lmrDepth) <= alpha {
+					continue
+				}
+
+				// SEE pruning of the quiet moves losing material
+				if s.sel.SEEPruning && lmrDepth < 8 && !s.pos.SEE(m, Value(-20*lmrDepth*lmrDepth)) {
+					continue
+				}
+			}
+		}
+
+		// Extensions, a line can be extended by at most rootDepth plies so
+		// the tree stays bounded
+		extension := 0
+		if ply != 0 && ply < 2*s.rootDepth && s.ss(ply-1).extensions < s.rootDepth {
+			switch {
+			// Singular extension, the TT move is extended if all the other
+			// moves fail low against a bound below its value. If instead
+			// another move fails high against a bound above beta, more than
+			// one move is likely to fail high (multi-cut) and the node is
+			// pruned.
+			case m == ttMove && excludedMove == MoveNone && depth >= 6 && tte.Bound&BoundLower != 0 &&
+				tte.Depth >= depth-3 && ttValue < ValueMateInMaxPly && ttValue > ValueMatedInMaxPly:
+				singularBeta := ttValue - Value(2*depth)
+
+				s.ss(ply).excludedMove = m
+				v := s.search(singularBeta-1, singularBeta, (depth-1)/2, ply)
+				s.ss(ply).excludedMove = MoveNone
+
+				if v < singularBeta {
+					extension = 1
+				} else if singularBeta >= beta {
+					return singularBeta
+				}
+
+			// Check extension, of the checks that do not lose material
+			case givesCheck && s.pos.SEE(m, ValueZero):
+				extension = 1
+
+			// Recapture extension, at PV nodes
+			case pvNode && capture && s.pos.LastCapturedPiece() != NoPiece &&
+				s.ss(ply-1).currentMove.IsOK() && s.ss(ply-1).currentMove.ToSquare() == m.ToSquare():
+				extension = 1
+			}
+		}
+		s.ss(ply).extensions = s.ss(ply-1).extensions + extension
+		newDepth += extension
+
+		s.doMove(ply, m, givesCheck)
+		var v Value
+		if depth >= 2 && moveCount > 1 && (!capture || !pvNode) && s.sel.LMR {
+			// Late move reductions, the moves ordered late are searched with
+			// a reduced depth and only searched fully if they beat alpha.
+			if pvNode {
+				r--
+			}
+			if mp.isReturnedRefutation(m) {
+				r--
+			}
+			if !capture {
+				pc := s.pos.PieceOn(m.ToSquare())
+				stat := int(s.h.main[us][fromTo(m)]) + int(s.contHist(ply)[0][pc][m.ToSquare()]) + int(s.contHist(ply)[1][pc][m.ToSquare()])
+				r -= stat / 12000
+			}
+
+			d := newDepth - r
+			if d > newDepth {
+				d = newDepth
+			} else if d < 1 {
+				d = 1
+			}
+
+			v = -s.search(-alpha-1, -alpha, d, ply+1)
+			if v > alpha && d < newDepth {
+				v = -s.search(-alpha-1, -alpha, newDepth, ply+1)
+			}
+		} else if !pvNode || moveCount > 1 {
+			v = -s.search(-alpha-1, -alpha, newDepth, ply+1)
+		}
+
+		// Search the first move of a PV node with the full window, and the
+		// rest again with the full window only if they may improve alpha.
+		if pvNode && (moveCount == 1 || (v > alpha && v < beta)) {
+			v = -s.search(-beta, -alpha, newDepth, ply+1)
+		}
+		s.pos.UndoMove(m)
+
+		if s.stopped() {
+			return ValueZero
+		}
+
+		if v > best {
+			best = v
+			if v > alpha {
+				// The best move changes at the root, used by the time
+				// management
+				if ply == 0 && moveCount > 1 {
+					s.bestMoveChanges++
+				}
+
+				alpha = v
+				bestMove = m
+				s.updatePV(ply, m)
+				if v >= beta {
+					break
+				}
+			}
+		}
+
+		if capture {
+			capturesSearched = append(capturesSearched, m)
+		} else {
+			quietsSearched = append(quietsSearched, m)
+		}
+	}
+
+	if moveCount == 0 {
+		if excludedMove != MoveNone {
+			return alpha
+		}
+		if inCheck {
+			return MatedIn(ply)
+		}
+		return ValueDraw
+	}
+
+	if best >= beta {
+		s.updateStats(ply, bestMove, depth, quietsSearched, capturesSearched)
+	}
+
+	bound := BoundUpper
+	if best >= beta {
+		bound = BoundLower
+	} else if pvNode && best > oldAlpha {
+		bound = BoundExact
+	}
+	if excludedMove == MoveNone {
+		s.tt.Store(s.pos.Key(), valueToTT(best, ply), bound, depth, bestMove, eval)
+	}
+
+	return best
+}
+
+// boundFor() returns the bound that makes a value usable as a fail high, or
+// as a fail low.
+func boundFor(failHigh bool) Bound {
+	if failHigh {
+		return BoundLower
+	}
+	return BoundUpper
+}
+
+// The quiescence search depths, its first ply also searches the quiet checks
+// so that the threats of mate are not missed at the horizon.
+const (
+	depthQSChecks   = 0
+	depthQSNoChecks = -1
+)
+
+// qsearch() searches only the captures and queen promotions, or all the
+// evasions when in check, until the position is quiet. At depthQSChecks the
+// quiet checks are searched too.
+func (s *searcher) qsearch(alpha, beta Value, depth, ply int) Value {
+	s.pvLen[ply] = ply
+	atomic.AddUint64(&s.nodes, 1)
+	s.checkLimits()
+	if ply > s.selDepth {
+		s.selDepth = ply
+	}
+
+	if s.stopped() {
+		return ValueZero
+	}
+	if s.pos.IsDraw(ply) {
+		return ValueDraw
+	}
+
+	inCheck := s.pos.Checkers() != 0
+	if ply >= MaxPly {
+		if inCheck {
+			return ValueDraw
+		}
+		return Evaluate(s.pos)
+	}
+
+	pvNode := beta-alpha > 1
+	tte, ttHit := s.tt.Probe(s.pos.Key())
+	ttValue, ttMove := ValueNone, MoveNone
+	if ttHit {
+		ttValue = valueFromTT(tte.Value, ply)
+		ttMove = tte.Move
+	}
+	// The entries searched with the quiet checks are good for either depth
+	ttDepth := depthQSNoChecks
+	if inCheck || depth >= depthQSChecks {
+		ttDepth = depthQSChecks
+	}
+	if !pvNode && ttHit && tte.Depth >= ttDepth && ttValue != ValueNone && tte.Bound&boundFor(ttValue >= beta) != 0 {
+		return ttValue
+	}
+
+	best, bestMove := -ValueInfinite, MoveNone
+	eval := ValueNone
+	if !inCheck {
+		if ttHit && tte.Eval != ValueNone {
+			eval = tte.Eval
+		} else {
+			eval = Evaluate(s.pos)
+		}
+
+		// Stand pat, the side to move can usually do at least as good as the
+		// static evaluation by playing a quiet move.
+		best = eval
+		if best >= beta {
+			if !ttHit {
+				s.tt.Store(s.pos.Key(), valueToTT(best, ply), BoundLower, ttDepth, MoveNone, eval)
+			}
+			return best
+		}
+		if best > alpha {
+			alpha = best
+		}
+	}
+
+	mp := newQSearchPicker(s.pos, s.h, ttMove, s.contHist(ply), depth >= depthQSChecks)
+	for m := mp.next(false); m != MoveNone; m = mp.next(false) {
+		if !s.pos.IsMoveLegal(m) {
+			continue
+		}
+
+		// Skip the captures losing material, once a move not being mated is
+		// found
+		if best > ValueMatedInMaxPly && !s.pos.SEE(m, ValueZero) {
+			continue
+		}
+
+		s.doMove(ply, m, s.pos.GivesCheck(m))
+		v := -s.qsearch(-beta, -alpha, depth-1, ply+1)
+		s.pos.UndoMove(m)
+
+		if s.stopped() {
+			return ValueZero
+		}
+
+		if v > best {
+			best = v
+			if v > alpha {
+				alpha = v
+				bestMove = m
+				s.updatePV(ply, m)
+				if v >= beta {
+					break
+				}
+			}
+		}
+	}
+
+	// All the evasions were searched, so no legal move means checkmate
+	if inCheck && best == -ValueInfinite {
+		return MatedIn(ply)
+	}
+
+	bound := BoundUpper
+	if best >= beta {
+		bound = BoundLower
+	}
+	s.tt.Store(s.pos.Key(), valueToTT(best, ply), bound, ttDepth, bestMove, eval)
+
+	return best
+}
+
+// isSacrifice() tests whether the previous move is a quiet move leaving the
+// moved piece to be won by a capture.
+func (s *searcher) isSacrifice(ply int) bool {
+	prev := s.ss(ply - 1).currentMove
+	if !prev.IsOK() || s.pos.LastCapturedPiece() != NoPiece {
+		return false
+	}
+
+	// The capture with the least valuable attacker
+	to := prev.ToSquare()
+	attackers := s.pos.AttackersTo(to) & s.pos.PiecesByColor(s.pos.SideToMove())
+	for pt := Pawn; pt <= King; pt++ {
+		if b := attackers & s.pos.PiecesByType(pt); b != 0 {
+			return s.pos.SEE(NewSimpleMove(b.lsb(), to), 1)
+		}
+	}
+	return false
+}
+
+func (s *searcher) updatePV(ply int, m Move) {
+	s.pv[ply][ply] = m
+	copy(s.pv[ply][ply+1:], s.pv[ply+1][ply+1:s.pvLen[ply+1]])
+	s.pvLen[ply] = s.pvLen[ply+1]
+}
+
+func (s *searcher) ss(ply int) *stackEntry {
+	return &s.stack[ply+stackOffset]
+}
+
+// contHist() returns the continuation histories of the moves 1, 2 and 4
+// plies before ply.
+func (s *searcher) contHist(ply int) [3]*pieceToHistory {
+	return [3]*pieceToHistory{s.ss(ply - 1).contHist, s.ss(ply - 2).contHist, s.ss(ply - 4).contHist}
+}
+
+// doMove() plays m at ply, keeping track of it in the stack.
+func (s *searcher) doMove(ply int, m Move, givesCheck bool) {
+	ss := s.ss(ply)
+	ss.currentMove = m
+	ss.contHist = &s.h.continuation[s.pos.MovedPiece(m)][m.ToSquare()]
+	s.pos.doMove(m, givesCheck)
+}
+
+// updateStats() updates the move ordering statistics after bestMove caused a
+// beta cutoff, rewarding it and penalizing the moves searched before it.
+func (s *searcher) updateStats(ply int, bestMove Move, depth int, quiets, captures []Move) {
+	bonus := statBonus(depth)
+
+	if !s.pos.IsMoveCapture(bestMove) {
+		s.updateQuietStats(ply, bestMove, bonus)
+		for _, m := range quiets {
+			s.updateQuietHistories(ply, m, -bonus)
+		}
+	} else {
+		s.updateCaptureHistory(bestMove, bonus)
+	}
+
+	for _, m := range captures {
+		s.updateCaptureHistory(m, -bonus)
+	}
+}
+
+// updateQuietStats() updates the killers, the countermove and the histories
+// of a quiet move that caused a cutoff.
+func (s *searcher) updateQuietStats(ply int, m Move, bonus int) {
+	ss := s.ss(ply)
+	if ss.killers[0] != m {
+		ss.killers[1] = ss.killers[0]
+		ss.killers[0] = m
+	}
+
+	if prev := s.ss(ply - 1).currentMove; prev.IsOK() {
+		prevSq := prev.ToSquare()
+		s.h.counterMoves[s.pos.PieceOn(prevSq)][prevSq] = m
+	}
+
+	s.updateQuietHistories(ply, m, bonus)
+}
+
+func (s *searcher) updateQuietHistories(ply int, m Move, bonus int) {
+	us := s.pos.SideToMove()
+	updateHistory(&s.h.main[us][fromTo(m)], bonus, butterflyHistoryD)
+
+	pc := s.pos.MovedPiece(m)
+	to := m.ToSquare()
+	for _, i := range [...]int{1, 2, 4} {
+		if ss := s.ss(ply - i); ss.currentMove.IsOK() {
+			updateHistory(&ss.contHist[pc][to], bonus, pieceToHistoryD)
+		}
+	}
+}
+
+func (s *searcher) updateCaptureHistory(m Move, bonus int) {
+	pc := s.pos.MovedPiece(m)
+	captured := s.pos.CapturedPiece(m).Type()
+	updateHistory(&s.h.capture[pc][m.ToSquare()][captured], bonus, captureHistoryD)
+}
+
+func containsMove(moves []Move, m Move) bool {
+	for _, x := range moves {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+// search() searches fen with the default selectivity, it returns the best
+// move and the nodes searched.
+func search(t *testing.T, fen string, limits uci.EngineSearchLimits, threads int) (Move, uint64) {
+	t.Helper()
+	pos, err := NewPosition(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+
+	var stop int32
+	tm := newTimeManager(limits, pos.SideToMove(), pos.GamePly(), DefaultMoveOverhead, false)
+	tp := newThreadPool(threads)
+	best, _ := tp.search(pos, limits, tm, NewTranspositionTable(16), DefaultSelectivity(), &stop, out, func() {})
+	close(out)
+	<-done
+
+	return best, tp.nodes()
+}
+
+func TestSearchTactics(t *testing.T) {
+	tests := []struct {
+		id    string
+		fen   string
+		depth int
+		best  string
+	}{
+		{"WAC.001", "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1", 6, "g3g6"},
+		{"WAC.003", "5rk1/1ppb3p/p1pb4/6q1/3P1p1r/2P1R2P/PP1BQ1P1/5RKN w - - 0 1", 6, "e3g3"},
+		{"WAC.004", "r1bq2rk/pp3pbp/2p1p1pQ/7P/3P4/2PB1N2/PP3PPR/2KR4 w - - 0 1", 6, "h6h7"},
+		{"WAC.005", "5k2/6pp/p1qN4/1p1p4/3P4/2PKP2Q/PP3r2/3R4 b - - 0 1", 6, "c6c4"},
+	}
+
+	for _, tt := range tests {
+		if best, _ := search(t, tt.fen, uci.EngineSearchLimits{Depth: tt.depth}, 1); best.String() != tt.best {
+			t.Errorf("%s: depth %d: got %s, want %s", tt.id, tt.depth, best, tt.best)
+		}
+	}
+}
+
+func TestSearchNodes(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		for _, limit := range []int{5000, 50000} {
+			// Every thread may search a few nodes after the limit is reached
+			_, nodes := search(t, StartFEN, uci.EngineSearchLimits{Nodes: limit}, threads)
+			if nodes < uint64(limit) || nodes > uint64(limit+200*threads) {
+				t.Errorf("threads %d: go nodes %d searched %d nodes", threads, limit, nodes)
+			}
+		}
+	}
+}
+
+func TestSearchMate(t *testing.T) {
+	// Without a mate the search ends after 2*Mate plies
+	if best, _ := search(t, StartFEN, uci.EngineSearchLimits{Mate: 2}, 1); best == MoveNone {
+		t.Errorf("go mate 2: no best move")
+	}
+	best, _ := search(t, "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1", uci.EngineSearchLimits{Mate: 2}, 1)
+	if best.String() != "g3g6" {
+		t.Errorf("go mate 2: got %s, want g3g6", best)
+	}
+}
@@ -0,0 +1,160 @@
+package engine
+
+// Static Exchange Evaluation computes the material balance of the sequence of
+// captures on the destination square of a move, where both sides capture with
+// their least valuable attacker and may stop capturing at any time.
+// Attackers behind the moving pieces (x-rays) join the exchange as the
+// occupancy changes, while pinned pieces cannot capture as long as their
+// pinners are on the board.
+
+// seeStart() returns the occupancy after m, the value gained by m and the
+// value of the piece left on the destination square.
+func (p *Position) seeStart(m Move) (occupied Bitboard, gain, onSquare Value) {
+	from, to := m.FromSquare(), m.ToSquare()
+	occupied = p.PiecesByType(AllPieces) ^ from.Bitboard()
+
+	gain = PieceValue[p.CapturedPiece(m).Type()]
+	onSquare = PieceValue[p.MovedPiece(m).Type()]
+
+	switch m.Type() {
+	case EnPassant:
+		occupied ^= (to - Square(PawnPush(p.sideToMove))).Bitboard()
+	case Promotion:
+		gain += PieceValue[m.PromotionType()] - PieceValue[Pawn]
+		onSquare = PieceValue[m.PromotionType()]
+	}
+
+	return occupied | to.Bitboard(), gain, onSquare
+}
+
+// seeNextAttacker() finds the least valuable attacker of to of color stm,
+// removes it from occupied and adds the x-ray attackers behind it. It returns
+// NoPieceType if stm has no attacker that can take part in the exchange.
+func (p *Position) seeNextAttacker(to Square, stm Color, occupied *Bitboard, attackers *Bitboard) PieceType {
+	*attackers &= *occupied
+	stmAttackers := *attackers & p.PiecesByColor(stm)
+
+	// Don't allow pinned pieces to attack as long as there are pinners on
+	// their original square.
+	if p.Pinners(1-stm)&*occupied != 0 {
+		stmAttackers &^= p.KingBlockers(stm)
+	}
+	if stmAttackers == 0 {
+		return NoPieceType
+	}
+
+	for pt := Pawn; pt <= King; pt++ {
+		b := stmAttackers & p.PiecesByType(pt)
+		if b == 0 {
+			continue
+		}
+
+		// The king can only capture if the square is not defended anymore
+		if pt == King && *attackers&^p.PiecesByColor(stm) != 0 {
+			return NoPieceType
+		}
+
+		*occupied ^= b.lsb().Bitboard()
+		if pt == Pawn || pt == Bishop || pt == Queen {
+			*attackers |= AttacksBB(Bishop, to, *occupied) & (p.PiecesByType(Bishop) | p.PiecesByType(Queen))
+		}
+		if pt == Rook || pt == Queen {
+			*attackers |= AttacksBB(Rook, to, *occupied) & (p.PiecesByType(Rook) | p.PiecesByType(Queen))
+		}
+		return pt
+	}
+
+	return NoPieceType
+}
+
+// SEE() tests whether the static exchange evaluation of m is greater than or
+// equal to threshold.
+func (p *Position) SEE(m Move, threshold Value) bool {
+	// Castling never wins or loses material
+	if m.Type() == Castling {
+		return ValueZero >= threshold
+	}
+
+	to := m.ToSquare()
+	occupied, gain, onSquare := p.seeStart(m)
+
+	// swap is the balance, relative to threshold, if the opponent captures
+	// the piece on the square and the exchange stops.
+	swap := gain - threshold
+	if swap < 0 {
+		return false
+	}
+	swap = onSquare - swap
+	if swap <= 0 {
+		return true
+	}
+
+	stm := p.sideToMove
+	attackers := p.attackersTo(to, occupied)
+	res := true
+
+	for {
+		stm = 1 - stm
+		pt := p.seeNextAttacker(to, stm, &occupied, &attackers)
+		if pt == NoPieceType {
+			break
+		}
+		res = !res
+
+		// Capturing with the king ends the exchange, the opponent has no
+		// attackers left.
+		if pt == King {
+			break
+		}
+
+		swap = PieceValue[pt] - swap
+		if res {
+			if swap < 1 {
+				break
+			}
+		} else if swap < 0 {
+			break
+		}
+	}
+
+	return res
+}
+
+// SEEValue() returns the static exchange evaluation of m, the material won by
+// the side to move at the end of the best sequence of captures.
+func (p *Position) SEEValue(m Move) Value {
+	if m.Type() == Castling {
+		return ValueZero
+	}
+
+	to := m.ToSquare()
+	occupied, captured, onSquare := p.seeStart(m)
+
+	var gain [32]Value
+	gain[0] = captured
+	d := 0
+
+	stm := p.sideToMove
+	attackers := p.attackersTo(to, occupied)
+
+	for {
+		stm = 1 - stm
+		pt := p.seeNextAttacker(to, stm, &occupied, &attackers)
+		if pt == NoPieceType {
+			break
+		}
+
+		d++
+		gain[d] = onSquare - gain[d-1]
+		onSquare = PieceValue[pt]
+	}
+
+	// Each side can stop capturing when it would lose material
+	for ; d > 0; d-- {
+		if -gain[d-1] < gain[d] {
+			gain[d-1] = -gain[d]
+		}
+	}
+
+	return gain[0]
+}
@@ -0,0 +1,48 @@
+package engine
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move string
+		see  Value
+	}{
+		{"free pawn", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", 82},
+		{"defended pawn", "4k3/2p5/3p4/8/8/8/8/3RK3 w - - 0 1", "d1d6", 82 - 477},
+		{"quiet move to an attacked square", "4k3/8/8/3p4/8/2N5/8/4K3 w - - 0 1", "c3e4", -337},
+		{"x-ray attacker", "3rk3/8/8/3p4/8/8/3R4/3RK3 w - - 0 1", "d2d5", 82},
+		{"x-ray defender", "3qk3/3r4/8/3p4/8/8/3R4/3RK3 w - - 0 1", "d2d5", 82 - 477},
+		{"x-ray behind a pawn", "4k3/8/5p2/4p3/3P4/2B5/8/4K3 w - - 0 1", "d4e5", 82},
+		{"pinned defender", "8/4k3/5n2/R2p4/7B/8/8/4K3 w - - 0 1", "a5d5", 82},
+		{"capture of the pinned piece", "8/4k3/5n2/3p4/7B/8/8/3RK3 w - - 0 1", "h4f6", 337 - 365},
+		{"defending king", "4k3/4p3/8/8/8/8/8/4RK2 w - - 0 1", "e1e7", 82 - 477},
+		{"king that cannot recapture", "4k3/4p3/8/8/8/8/4R3/4R1K1 w - - 0 1", "e2e7", 82},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", 1025 - 82},
+		{"underpromotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", 337 - 82},
+		{"defended promotion", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", 1025 - 82 - 1025},
+		{"capture promotion", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8q", 477 + 1025 - 82},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 82},
+		{"defended en passant", "4k3/2p5/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 0},
+		{"castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", 0},
+	}
+
+	for _, tt := range tests {
+		pos, err := NewPosition(tt.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := pos.NewUCIMove(tt.move)
+		if m == MoveNone {
+			t.Errorf("%s: illegal move %v", tt.name, tt.move)
+			continue
+		}
+		if got := pos.SEEValue(m); got != tt.see {
+			t.Errorf("%s: SEEValue() = %v, want %v", tt.name, got, tt.see)
+		}
+		if !pos.SEE(m, tt.see) || pos.SEE(m, tt.see+1) {
+			t.Errorf("%s: SEE() threshold is not %v", tt.name, tt.see)
+		}
+	}
+}
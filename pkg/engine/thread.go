@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+// MaxThreads is the maximum number of search threads.
+const MaxThreads = 512
+
+// threadPool runs a Lazy SMP search: all the threads search the root
+// position, sharing only the transposition table. The helper threads fill the
+// table with results the main thread can use, and search different depths so
+// they do not all search the same tree.
+type threadPool struct {
+	// histories holds the move ordering statistics of each thread, they are
+	// kept between searches
+	histories []*history
+	searchers []*searcher
+}
+
+func newThreadPool(n int) *threadPool {
+	tp := &threadPool{}
+	tp.resize(n)
+	return tp
+}
+
+// resize() sets the number of threads, keeping the histories of the
+// threads left.
+func (tp *threadPool) resize(n int) {
+	for len(tp.histories) < n {
+		tp.histories = append(tp.histories, newHistory())
+	}
+	tp.histories = tp.histories[:n]
+}
+
+func (tp *threadPool) clear() {
+	for _, h := range tp.histories {
+		h.clear()
+	}
+}
+
+// The helper threads skip some depths of the iterative deepening, the i-th
+// helper searches depth d if (d + ply + skipPhase[i]) / skipSize[i] is even.
+var (
+	skipSize  = [...]int{1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4}
+	skipPhase = [...]int{0, 1, 0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+)
+
+// search() searches pos with all the threads and returns the best move and
+// the expected reply, as voted by the threads. When the main thread finishes
+// its search wait() is called, then the helpers are stopped.
+func (tp *threadPool) search(pos *Position, limits uci.EngineSearchLimits, tm *timeManager, tt *TranspositionTable, sel Selectivity, stop *int32, out chan string, wait func()) (Move, Move) {
+	tp.searchers = tp.searchers[:0]
+	for i, h := range tp.histories {
+		// The main thread searches the position itself, the helpers need
+		// their own copy
+		p := pos
+		if i != 0 {
+			p = pos.Copy()
+		}
+		s := newSearcher(p, limits, tm, tt, h, sel, stop, out)
+		s.id, s.pool = i, tp
+		tp.searchers = append(tp.searchers, s)
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range tp.searchers[1:] {
+		wg.Add(1)
+		go func(s *searcher) {
+			defer wg.Done()
+			s.iterate()
+		}(s)
+	}
+
+	main := tp.searchers[0]
+	main.iterate()
+	wait()
+
+	atomic.StoreInt32(stop, 1)
+	wg.Wait()
+
+	best := tp.bestSearcher()
+	if best != main {
+		best.info()
+	}
+
+	bm, po := best.result()
+	if bm != MoveNone && po == MoveNone {
+		po = ponderMoveFromTT(pos, tt, bm)
+	}
+	return bm, po
+}
+
+// bestSearcher() returns the thread whose best move is played. Each thread
+// votes for its best move, with a weight growing with its depth and score,
+// except that the shortest mate found is always preferred.
+func (tp *threadPool) bestSearcher() *searcher {
+	best := tp.searchers[0]
+
+	minValue := ValueInfinite
+	for _, s := range tp.searchers {
+		if s.completedDepth > 0 && s.bestValue < minValue {
+			minValue = s.bestValue
+		}
+	}
+
+	votes := make(map[Move]int)
+	for _, s := range tp.searchers {
+		if s.completedDepth > 0 {
+			votes[s.prevPV[0]] += int(s.bestValue-minValue+14) * s.completedDepth
+		}
+	}
+
+	for _, s := range tp.searchers[1:] {
+		if s.completedDepth == 0 {
+			continue
+		}
+		if best.bestValue >= ValueMateInMaxPly {
+			if s.bestValue > best.bestValue {
+				best = s
+			}
+		} else if s.bestValue >= ValueMateInMaxPly ||
+			(s.bestValue > ValueMatedInMaxPly && votes[s.prevPV[0]] > votes[best.prevPV[0]]) {
+			best = s
+		}
+	}
+
+	return best
+}
+
+// nodes() returns the number of nodes searched by all the threads.
+func (tp *threadPool) nodes() uint64 {
+	var n uint64
+	for _, s := range tp.searchers {
+		n += atomic.LoadUint64(&s.nodes)
+	}
+	return n
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+// DefaultMoveOverhead and MaxMoveOverhead are the default and maximum time,
+// in milliseconds, reserved for each move to cover the communication delays
+// with the GUI.
+const (
+	DefaultMoveOverhead = 10
+	MaxMoveOverhead     = 5000
+)
+
+// timeManager decides how long the search of a move lasts. The search
+// normally stops after the optimum time, which grows when the best move is
+// unstable or the score drops, and it never lasts longer than the maximum
+// time. While pondering the clock is not running, the time limits only apply
+// after the ponderhit.
+type timeManager struct {
+	start   time.Time
+	timed   bool // false if the search is only stopped by the other limits
+	optimum time.Duration
+	maximum time.Duration
+
+	// The fields below are accessed atomically, they change on ponderhit.
+	// clockStart is the time in nanoseconds our clock started, at the start
+	// of the search or at the ponderhit, and stopOnPonderhit is set if the
+	// search would have stopped if it was not pondering.
+	clockStart      int64
+	pondering       int32
+	stopOnPonderhit int32
+}
+
+// newTimeManager() returns the time manager of a search. With ponder set the
+// GUI allows pondering, so we can expect to save some time on the ponderhits
+// and use more of it.
+func newTimeManager(limits uci.EngineSearchLimits, us Color, ply int, overhead time.Duration, ponder bool) *timeManager {
+	tm := &timeManager{start: time.Now()}
+	tm.clockStart = tm.start.UnixNano()
+	if limits.Ponder {
+		tm.pondering = 1
+	}
+
+	clock, inc := limits.WTime, limits.WInc
+	if us == Black {
+		clock, inc = limits.BTime, limits.BInc
+	}
+
+	switch {
+	case limits.MoveTime > 0:
+		tm.timed = true
+		tm.optimum = time.Duration(limits.MoveTime) * time.Millisecond
+		tm.maximum = tm.optimum
+	case limits.WTime != 0 || limits.BTime != 0:
+		tm.timed = true
+		tm.optimum, tm.maximum = allocateTime(
+			time.Duration(clock)*time.Millisecond, time.Duration(inc)*time.Millisecond,
+			limits.MovesToGo, ply, overhead)
+		if ponder {
+			tm.optimum += tm.optimum / 4
+			if tm.optimum > tm.maximum {
+				tm.optimum = tm.maximum
+			}
+		}
+	}
+
+	return tm
+}
+
+// allocateTime() returns the optimum and maximum time of a move, given the
+// time left on the clock, the increment and the moves to the next time
+// control (zero for sudden death). The time is spread over at most 50 moves,
+// with more of it used as the game progresses.
+func allocateTime(clock, inc time.Duration, movesToGo, ply int, overhead time.Duration) (optimum, maximum time.Duration) {
+	mtg := 50
+	if movesToGo > 0 && movesToGo < mtg {
+		mtg = movesToGo
+	}
+
+	// The time left for the next mtg moves, keeping the overhead of each
+	timeLeft := clock + inc*time.Duration(mtg-1) - overhead*time.Duration(2+mtg)
+	if timeLeft < time.Millisecond {
+		timeLeft = time.Millisecond
+	}
+
+	var optScale, maxScale float64
+	if movesToGo == 0 {
+		optScale = math.Min(0.0120+math.Pow(float64(ply)+3, 0.45)*0.0039, 0.2*float64(clock)/float64(timeLeft))
+		maxScale = math.Min(7, 4+float64(ply)/12)
+	} else {
+		optScale = math.Min((0.88+float64(ply)/116.4)/float64(mtg), 0.88*float64(clock)/float64(timeLeft))
+		maxScale = math.Min(6.3, 1.5+0.11*float64(mtg))
+	}
+
+	optimum = time.Duration(optScale * float64(timeLeft))
+	maximum = time.Duration(maxScale * float64(optimum))
+	if limit := time.Duration(0.8*float64(clock)) - overhead; maximum > limit {
+		maximum = limit
+	}
+	maximum -= 10 * time.Millisecond
+
+	if maximum < time.Millisecond {
+		maximum = time.Millisecond
+	}
+	if optimum < time.Millisecond {
+		optimum = time.Millisecond
+	}
+	if optimum > maximum {
+		optimum = maximum
+	}
+	return optimum, maximum
+}
+
+// elapsed() returns the time since our clock started.
+func (tm *timeManager) elapsed() time.Duration {
+	return time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&tm.clockStart))
+}
+
+// timeLimited() tests whether the search is stopped by the time limits.
+func (tm *timeManager) timeLimited() bool {
+	return tm.timed && atomic.LoadInt32(&tm.pondering) == 0
+}
+
+// ponderhit() starts the clock of a ponder search. It returns true if the
+// search should stop at once, because it would have already stopped.
+func (tm *timeManager) ponderhit() bool {
+	atomic.StoreInt64(&tm.clockStart, time.Now().UnixNano())
+	atomic.StoreInt32(&tm.pondering, 0)
+	return atomic.LoadInt32(&tm.stopOnPonderhit) != 0
+}
+
+// stopIteration() tests whether a new iteration should not be started, while
+// pondering it is only recorded so that the search stops on the ponderhit.
+// A single legal move is played at once, otherwise bestMoveChanges is the
+// decaying number of times the best move changed, and drop is how much the
+// score dropped since the previous iteration.
+func (tm *timeManager) stopIteration(singleReply bool, bestMoveChanges float64, drop Value) bool {
+	fallingEval := 1 + float64(drop)/100
+	if fallingEval < 0.5 {
+		fallingEval = 0.5
+	} else if fallingEval > 1.5 {
+		fallingEval = 1.5
+	}
+	instability := 1 + 1.7*bestMoveChanges
+
+	total := time.Duration(float64(tm.optimum) * fallingEval * instability)
+	if total > tm.maximum {
+		total = tm.maximum
+	}
+
+	// The next iteration takes longer than all the previous ones, there is
+	// no point in starting it if it will most likely be interrupted
+	if !singleReply && tm.elapsed() <= total/2 {
+		return false
+	}
+	if atomic.LoadInt32(&tm.pondering) != 0 {
+		atomic.StoreInt32(&tm.stopOnPonderhit, 1)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/uci"
+)
+
+func TestAllocateTime(t *testing.T) {
+	ms := time.Millisecond
+	for _, clock := range []time.Duration{-50 * ms, 0, 5 * ms, 100 * ms, 1000 * ms, 60000 * ms, 3600000 * ms} {
+		for _, inc := range []time.Duration{0, 100 * ms, 2000 * ms} {
+			for _, movesToGo := range []int{0, 1, 2, 10, 40, 100} {
+				for _, ply := range []int{0, 40, 200} {
+					for _, overhead := range []time.Duration{0, DefaultMoveOverhead * ms, 1000 * ms} {
+						optimum, maximum := allocateTime(clock, inc, movesToGo, ply, overhead)
+						if optimum <= 0 || optimum > maximum {
+							t.Errorf("clock %v inc %v movestogo %v ply %v overhead %v: optimum %v, maximum %v",
+								clock, inc, movesToGo, ply, overhead, optimum, maximum)
+						}
+
+						// The maximum keeps the overhead and a fifth of the
+						// clock, unless there is no time left
+						if limit := time.Duration(0.8*float64(clock)) - overhead - 10*ms; maximum > limit && maximum > ms {
+							t.Errorf("clock %v inc %v movestogo %v ply %v overhead %v: maximum %v over %v",
+								clock, inc, movesToGo, ply, overhead, maximum, limit)
+						}
+
+						// A larger overhead never leaves more time, up to the
+						// rounding
+						if opt, max := allocateTime(clock, inc, movesToGo, ply, overhead+100*ms); opt > optimum+time.Microsecond || max > maximum {
+							t.Errorf("clock %v inc %v movestogo %v ply %v overhead %v: more overhead allocates %v, %v",
+								clock, inc, movesToGo, ply, overhead, opt, max)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Sudden death spreads the clock over many moves, the last move before
+	// the time control may use most of it
+	if optimum, _ := allocateTime(60*time.Second, 0, 0, 0, DefaultMoveOverhead*ms); optimum < time.Second || optimum > 3*time.Second {
+		t.Errorf("sudden death: optimum %v", optimum)
+	}
+	if optimum, maximum := allocateTime(10*time.Second, 0, 1, 80, DefaultMoveOverhead*ms); optimum < 5*time.Second || maximum > 8*time.Second {
+		t.Errorf("movestogo 1: optimum %v, maximum %v", optimum, maximum)
+	}
+}
+
+func TestNewTimeManager(t *testing.T) {
+	ms := time.Millisecond
+	overhead := DefaultMoveOverhead * ms
+
+	if tm := newTimeManager(uci.EngineSearchLimits{Depth: 10}, White, 0, overhead, false); tm.timed {
+		t.Error("go depth: timed search")
+	}
+	if tm := newTimeManager(uci.EngineSearchLimits{MoveTime: 500, WTime: 1000}, White, 0, overhead, false); !tm.timed || tm.optimum != 500*ms || tm.maximum != 500*ms {
+		t.Errorf("go movetime 500: optimum %v, maximum %v", tm.optimum, tm.maximum)
+	}
+
+	// The clock and increment of the side to move are used
+	limits := uci.EngineSearchLimits{WTime: 1000, BTime: 100000, WInc: 0, BInc: 1000}
+	white := newTimeManager(limits, White, 20, overhead, false)
+	black := newTimeManager(limits, Black, 20, overhead, false)
+	wOpt, wMax := allocateTime(1000*ms, 0, 0, 20, overhead)
+	bOpt, bMax := allocateTime(100000*ms, 1000*ms, 0, 20, overhead)
+	if white.optimum != wOpt || white.maximum != wMax || black.optimum != bOpt || black.maximum != bMax {
+		t.Errorf("white %v %v, black %v %v", white.optimum, white.maximum, black.optimum, black.maximum)
+	}
+
+	// Pondering uses more time, the clock only runs after the ponderhit
+	limits.Ponder = true
+	tm := newTimeManager(limits, Black, 20, overhead, true)
+	if tm.optimum != bOpt+bOpt/4 || tm.maximum != bMax || tm.timeLimited() {
+		t.Errorf("ponder: optimum %v, maximum %v", tm.optimum, tm.maximum)
+	}
+	tm.ponderhit()
+	if !tm.timeLimited() {
+		t.Error("ponderhit: no time limit")
+	}
+}
@@ -0,0 +1,228 @@
+package engine
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// DefaultHashSize and MaxHashSize are the default and maximum size of the
+// transposition table, in megabytes.
+const (
+	DefaultHashSize = 16
+	MaxHashSize     = 1 << 16
+)
+
+type Bound int
+
+const (
+	BoundNone  Bound = 0
+	BoundUpper Bound = 1
+	BoundLower Bound = 2
+	BoundExact       = BoundUpper | BoundLower
+)
+
+// TTEntry is the decoded content of a transposition table entry.
+type TTEntry struct {
+	Move  Move
+	Value Value
+	Eval  Value
+	Depth int
+	Bound Bound
+}
+
+// ttEntry is stored in two 64 bit words so it can be read and written without
+// locks. The key is stored xored with the data, so an entry torn by a
+// concurrent write does not match any key and is ignored.
+//
+// bit  0- 7: depth - depthOffset
+// bit  8- 9: bound
+// bit 10-15: generation
+// bit 16-31: move
+// bit 32-47: value
+// bit 48-63: static evaluation
+type ttEntry struct {
+	key  uint64
+	data uint64
+}
+
+const (
+	clusterSize = 4
+
+	// depthOffset is lower than any depth stored, so an empty entry has a
+	// stored depth of zero
+	depthOffset = -8
+
+	generationShift = 10
+	generationMask  = 0x3f
+)
+
+// A cluster fits a cache line, all the entries of a position are in a single
+// cluster.
+type ttCluster struct {
+	entries [clusterSize]ttEntry
+}
+
+// TranspositionTable is a hash table of the searched positions, shared by all
+// the search threads. Entries are replaced by depth and age, the age being the
+// number of searches since the entry was written.
+type TranspositionTable struct {
+	clusters   []ttCluster
+	generation uint64
+}
+
+func NewTranspositionTable(mb int) *TranspositionTable {
+	tt := &TranspositionTable{}
+	tt.Resize(mb)
+	return tt
+}
+
+// Resize() sets the size of the table in megabytes, clearing it.
+func (tt *TranspositionTable) Resize(mb int) {
+	tt.clusters = make([]ttCluster, mb*1024*1024/64)
+}
+
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.clusters {
+		tt.clusters[i] = ttCluster{}
+	}
+	tt.generation = 0
+}
+
+// NewSearch() increases the age of all the entries, it is called before
+// every search.
+func (tt *TranspositionTable) NewSearch() {
+	tt.generation = (tt.generation + 1) & generationMask
+}
+
+func (tt *TranspositionTable) cluster(key Key) *ttCluster {
+	hi, _ := bits.Mul64(uint64(key), uint64(len(tt.clusters)))
+	return &tt.clusters[hi]
+}
+
+// Probe() looks up the position with the given key.
+func (tt *TranspositionTable) Probe(key Key) (TTEntry, bool) {
+	c := tt.cluster(key)
+	for i := range c.entries {
+		e := &c.entries[i]
+		data := atomic.LoadUint64(&e.data)
+		if atomic.LoadUint64(&e.key)^data == uint64(key) && data != 0 {
+			return decodeTTEntry(data), true
+		}
+	}
+	return TTEntry{}, false
+}
+
+// Store() saves a search result. It replaces the entry of the same position,
+// or an empty entry, or else the least valuable entry of the cluster, judged
+// by depth and age.
+func (tt *TranspositionTable) Store(key Key, v Value, b Bound, depth int, m Move, eval Value) {
+	c := tt.cluster(key)
+
+	replace := &c.entries[0]
+	replaceWorth := 1 << 30
+	for i := range c.entries {
+		e := &c.entries[i]
+		data := atomic.LoadUint64(&e.data)
+
+		if data == 0 || atomic.LoadUint64(&e.key)^data == uint64(key) {
+			// Keep the move of the position if there is no new one, and do
+			// not overwrite a deeper entry with a non exact bound, only its
+			// move.
+			if data != 0 {
+				old := decodeTTEntry(data)
+				if b != BoundExact && depth < old.Depth-3 && tt.age(data) == 0 {
+					if m != MoveNone && m != old.Move {
+						data = data&^(0xffff<<16) | uint64(uint16(m))<<16
+						atomic.StoreUint64(&e.key, uint64(key)^data)
+						atomic.StoreUint64(&e.data, data)
+					}
+					return
+				}
+				if m == MoveNone {
+					m = old.Move
+				}
+			}
+			replace = e
+			break
+		}
+
+		if worth := decodeTTEntry(data).Depth - 8*tt.age(data); worth < replaceWorth {
+			replace = e
+			replaceWorth = worth
+		}
+	}
+
+	data := uint64(uint8(depth-depthOffset)) |
+		uint64(b)<<8 |
+		tt.generation<<generationShift |
+		uint64(uint16(m))<<16 |
+		uint64(uint16(int16(v)))<<32 |
+		uint64(uint16(int16(eval)))<<48
+
+	atomic.StoreUint64(&replace.key, uint64(key)^data)
+	atomic.StoreUint64(&replace.data, data)
+}
+
+// age() returns the number of searches since data was stored.
+func (tt *TranspositionTable) age(data uint64) int {
+	return int((tt.generation - data>>generationShift&generationMask) & generationMask)
+}
+
+func decodeTTEntry(data uint64) TTEntry {
+	return TTEntry{
+		Depth: int(uint8(data)) + depthOffset,
+		Bound: Bound(data >> 8 & 3),
+		Move:  Move(uint16(data >> 16)),
+		Value: Value(int16(data >> 32)),
+		Eval:  Value(int16(data >> 48)),
+	}
+}
+
+// Hashfull() returns the permille of the table used by the current search,
+// estimated from the first thousand clusters.
+func (tt *TranspositionTable) Hashfull() int {
+	n := 1000
+	if n > len(tt.clusters) {
+		n = len(tt.clusters)
+	}
+
+	count := 0
+	for i := 0; i < n; i++ {
+		for j := range tt.clusters[i].entries {
+			data := atomic.LoadUint64(&tt.clusters[i].entries[j].data)
+			if data != 0 && tt.age(data) == 0 {
+				count++
+			}
+		}
+	}
+
+	return count * 1000 / (n * clusterSize)
+}
+
+// valueToTT() adjusts a mate score from "plies to mate from the root" to
+// "plies to mate from the current position", the way it is stored in the
+// transposition table.
+func valueToTT(v Value, ply int) Value {
+	switch {
+	case v >= ValueMateInMaxPly:
+		return v + Value(ply)
+	case v <= ValueMatedInMaxPly:
+		return v - Value(ply)
+	default:
+		return v
+	}
+}
+
+// valueFromTT() is the inverse of valueToTT().
+func valueFromTT(v Value, ply int) Value {
+	switch {
+	case v == ValueNone:
+		return v
+	case v >= ValueMateInMaxPly:
+		return v - Value(ply)
+	case v <= ValueMatedInMaxPly:
+		return v + Value(ply)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import "testing"
+
+// sameClusterKeys() returns n keys of the same cluster of a table of any
+// size.
+func sameClusterKeys(n int) []Key {
+	keys := make([]Key, n)
+	for i := range keys {
+		keys[i] = Key(0x8000000000000000 + uint64(i))
+	}
+	return keys
+}
+
+func TestTTStoreProbe(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	keys := sameClusterKeys(2)
+
+	if _, ok := tt.Probe(keys[0]); ok {
+		t.Fatal("hit in an empty table")
+	}
+
+	tests := []TTEntry{
+		{Move(0x1234), 150, -20, 12, BoundExact},
+		{Move(0xffff), -ValueMate + 10, ValueNone, 0, BoundUpper},
+		{MoveNone, ValueMate - 3, 0, -1, BoundLower},
+		{Move(1), 0, 1, MaxPly - 1, BoundUpper},
+	}
+	for _, want := range tests {
+		tt.Clear()
+		tt.Store(keys[0], want.Value, want.Bound, want.Depth, want.Move, want.Eval)
+		if got, ok := tt.Probe(keys[0]); !ok || got != want {
+			t.Errorf("stored %+v, got %+v %v", want, got, ok)
+		}
+
+		// A key of the same cluster does not match the entry
+		if got, ok := tt.Probe(keys[1]); ok {
+			t.Errorf("stored %+v, hit %+v with another key", want, got)
+		}
+	}
+}
+
+func TestTTReplacement(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	keys := sameClusterKeys(clusterSize + 2)
+	probe := func(key Key) TTEntry {
+		t.Helper()
+		e, ok := tt.Probe(key)
+		if !ok {
+			t.Fatalf("key %x: miss", key)
+		}
+		return e
+	}
+
+	// A shallow search of the same position does not replace the entry,
+	// only its move
+	tt.Store(keys[0], 100, BoundExact, 10, Move(1), 5)
+	tt.Store(keys[0], 50, BoundUpper, 3, Move(2), 5)
+	if e := probe(keys[0]); e.Depth != 10 || e.Value != 100 || e.Bound != BoundExact || e.Move != Move(2) {
+		t.Errorf("shallow non exact store: got %+v", e)
+	}
+	tt.Store(keys[0], 50, BoundLower, 4, MoveNone, 5)
+	if e := probe(keys[0]); e.Depth != 10 || e.Move != Move(2) {
+		t.Errorf("shallow store without a move: got %+v", e)
+	}
+
+	// An exact bound replaces the entry, keeping the move if there is no
+	// new one
+	tt.Store(keys[0], 30, BoundExact, 3, MoveNone, 5)
+	if e := probe(keys[0]); e.Depth != 3 || e.Value != 30 || e.Move != Move(2) {
+		t.Errorf("exact store: got %+v", e)
+	}
+
+	// So does a bound of an older search
+	tt.Store(keys[0], 100, BoundLower, 10, Move(3), 5)
+	tt.NewSearch()
+	tt.Store(keys[0], 50, BoundUpper, 3, Move(4), 5)
+	if e := probe(keys[0]); e.Depth != 3 || e.Move != Move(4) {
+		t.Errorf("store after a new search: got %+v", e)
+	}
+
+	// A full cluster replaces the shallowest entry
+	tt.Clear()
+	for i, depth := range []int{10, 5, 8, 12} {
+		tt.Store(keys[i], 0, BoundExact, depth, MoveNone, 0)
+	}
+	tt.Store(keys[4], 0, BoundExact, 1, MoveNone, 0)
+	if _, ok := tt.Probe(keys[1]); ok {
+		t.Error("the shallowest entry was not replaced")
+	}
+	for _, i := range []int{0, 2, 3, 4} {
+		probe(keys[i])
+	}
+
+	// or the oldest one, 8 plies of depth per search
+	tt.NewSearch()
+	for _, i := range []int{2, 3, 4} {
+		tt.Store(keys[i], 0, BoundExact, 5, MoveNone, 0)
+	}
+	tt.Store(keys[5], 0, BoundExact, 1, MoveNone, 0)
+	if _, ok := tt.Probe(keys[0]); ok {
+		t.Error("the oldest entry was not replaced")
+	}
+	for _, i := range []int{2, 3, 4, 5} {
+		probe(keys[i])
+	}
+}
+
+func TestTTHashfull(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	if got := tt.Hashfull(); got != 0 {
+		t.Errorf("empty table: got %v", got)
+	}
+	for i := range tt.clusters[:500] {
+		for j := 0; j < clusterSize/2; j++ {
+			tt.clusters[i].entries[j] = ttEntry{data: 1 | tt.generation<<generationShift}
+		}
+	}
+	if got := tt.Hashfull(); got != 250 {
+		t.Errorf("got %v, want 250", got)
+	}
+	tt.NewSearch()
+	if got := tt.Hashfull(); got != 0 {
+		t.Errorf("after a new search: got %v, want 0", got)
+	}
+}
@@ -1,6 +1,10 @@
 package engine
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
 
 type Color int
 
@@ -10,6 +14,21 @@ const (
 	ColorNB Color = 2
 )
 
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// CastlingRights() returns all the castling rights that belong to c.
+func (c Color) CastlingRights() CastlingRights {
+	if c == White {
+		return WhiteCastling
+	}
+	return BlackCastling
+}
+
 type MoveType int
 
 const (
@@ -68,6 +87,12 @@ func (m Move) PromotionType() PieceType {
 // String() converts a Move to a string in coordinate notation (g1f3, a7a8q).
 // Internally, all castling moves are always encoded as 'king captures rook'.
 func (m Move) String() string {
+	return m.UCIString(false)
+}
+
+// UCIString() is like String() but in Chess960 castling moves are written as
+// 'king captures rook' (e1h1), as the UCI protocol requires.
+func (m Move) UCIString(chess960 bool) string {
 	from := m.FromSquare()
 	to := m.ToSquare()
 
@@ -79,7 +104,7 @@ func (m Move) String() string {
 		return "0000"
 	}
 
-	if m.Type() == Castling {
+	if m.Type() == Castling && !chess960 {
 		if to > from {
 			to = NewSquare(FileG, from.Rank())
 		} else {
@@ -241,7 +266,7 @@ func (r Rank) Bitboard() Bitboard {
 }
 
 func (r Rank) String() string {
-	return string('0' + rune(r))
+	return string('1' + rune(r))
 }
 
 type Square int
@@ -357,7 +382,7 @@ func (s Square) File() File {
 }
 
 func (s Square) RelativeSquare(c Color) Square {
-	return Square(int(s) & (int(c) * 56))
+	return Square(int(s) ^ (int(c) * 56))
 }
 
 func (s Square) RelativeRank(c Color) Rank {
@@ -371,3 +396,42 @@ func (s Square) RankBB() Bitboard {
 func (s Square) String() string {
 	return s.File().String() + s.Rank().String()
 }
+
+// ParseSquare() converts a square in algebraic notation (e3, H8) to a Square.
+func ParseSquare(str string) (Square, error) {
+	if len(str) != 2 {
+		return SquareNone, fmt.Errorf("invalid square %q", str)
+	}
+	f := File(unicode.ToLower(rune(str[0])) - 'a')
+	r := Rank(str[1] - '1')
+	if f < FileA || f >= FileNB || r < Rank1 || r >= RankNB {
+		return SquareNone, fmt.Errorf("invalid square %q", str)
+	}
+	return NewSquare(f, r), nil
+}
+
+// MaxPly is the maximum depth the search can reach.
+const MaxPly = 246
+
+type Value int
+
+const (
+	ValueZero     Value = 0
+	ValueDraw     Value = 0
+	ValueMate     Value = 32000
+	ValueInfinite Value = 32001
+	ValueNone     Value = 32002
+
+	ValueMateInMaxPly  = ValueMate - MaxPly
+	ValueMatedInMaxPly = -ValueMateInMaxPly
+)
+
+// MateIn() returns the value of giving mate in ply plies.
+func MateIn(ply int) Value {
+	return ValueMate - Value(ply)
+}
+
+// MatedIn() returns the value of getting mated in ply plies.
+func MatedIn(ply int) Value {
+	return -ValueMate + Value(ply)
+}
@@ -0,0 +1,35 @@
+package engine
+
+// Key is a Zobrist hash key of a position.
+type Key uint64
+
+var (
+	zobristPsq       [PieceNB][SquareNB]Key
+	zobristEnpassant [FileNB]Key
+	zobristCastling  [CastlingRightsNB]Key
+	zobristSide      Key
+	zobristNoPawns   Key
+)
+
+// The keys are generated from a fixed seed, so the same position always
+// hashes to the same key across runs.
+func init() {
+	rng := NewPRNG(1070372)
+
+	for _, pc := range []Piece{WPawn, WKnight, WBishop, WRook, WQueen, WKing, BPawn, BKnight, BBishop, BRook, BQueen, BKing} {
+		for s := SquareA1; s <= SquareH8; s++ {
+			zobristPsq[pc][s] = Key(rng.Rand64())
+		}
+	}
+
+	for f := FileA; f <= FileH; f++ {
+		zobristEnpassant[f] = Key(rng.Rand64())
+	}
+
+	for cr := NoCastling; cr <= AnyCastling; cr++ {
+		zobristCastling[cr] = Key(rng.Rand64())
+	}
+
+	zobristSide = Key(rng.Rand64())
+	zobristNoPawns = Key(rng.Rand64())
+}
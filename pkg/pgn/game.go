@@ -0,0 +1,114 @@
+package pgn
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/engine"
+)
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Eval is an engine evaluation from the point of view of White.
+type Eval struct {
+	CP int
+	// Mate is the number of moves to mate, negative if White is getting
+	// mated, or zero if the evaluation is not a mate score.
+	Mate int
+}
+
+func (e Eval) String() string {
+	if e.Mate != 0 {
+		return "#" + strconv.Itoa(e.Mate)
+	}
+	return fmt.Sprintf("%.2f", float64(e.CP)/100)
+}
+
+// Node is a move of the game tree. The first child of a node continues the
+// line, the rest of the children are alternative moves, i.e. the variations.
+// The root node of a game has no move.
+type Node struct {
+	Move engine.Move
+	NAGs []int
+	// StartingComment is the comment before the move, it is only used for
+	// the first move of a variation.
+	StartingComment string
+	Comment         string
+	// Eval and Clock are optional annotations, written in the comment as
+	// [%eval] and [%clk] commands.
+	Eval  *Eval
+	Clock *time.Duration
+
+	Parent   *Node
+	Children []*Node
+}
+
+// AddChild() appends a new node with the move m to the children of n.
+func (n *Node) AddChild(m engine.Move) *Node {
+	c := &Node{Move: m, Parent: n}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// MainLine() returns the moves following n, always choosing the first child.
+func (n *Node) MainLine() []engine.Move {
+	var moves []engine.Move
+	for c := n; len(c.Children) != 0; {
+		c = c.Children[0]
+		moves = append(moves, c.Move)
+	}
+	return moves
+}
+
+type Game struct {
+	Tags []Tag
+	// Root holds the game tree, its comment is the comment before the first
+	// move.
+	Root *Node
+	// Moves is the main line of the game.
+	Moves  []engine.Move
+	Result string
+}
+
+func NewGame() *Game {
+	return &Game{Root: &Node{}, Result: "*"}
+}
+
+// Tag() returns the value of the named tag, or an empty string if the tag is
+// not present.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag() sets the value of the named tag, adding it if not present.
+func (g *Game) SetTag(name, value string) {
+	for i := range g.Tags {
+		if g.Tags[i].Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// StartPosition() returns the starting position of the game, as given by the
+// SetUp and FEN tags.
+func (g *Game) StartPosition() (*engine.Position, error) {
+	fen := g.Tag("FEN")
+	if fen == "" {
+		if g.Tag("SetUp") == "1" {
+			return nil, fmt.Errorf("SetUp tag without a FEN tag")
+		}
+		fen = engine.StartFEN
+	}
+	return engine.NewPosition(fen)
+}
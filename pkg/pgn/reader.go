@@ -0,0 +1,474 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseError is returned for a malformed game. The game is skipped and the
+// Reader can still be used to read the following games.
+type ParseError struct {
+	Game int // index of the game in the stream, starting from 1
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("pgn: game %d: line %d: %v", e.Game, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type tokenType int
+
+const (
+	tokSymbol tokenType = iota
+	tokString
+	tokComment
+	tokNAG
+	tokPeriod
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type token struct {
+	typ  tokenType
+	val  string
+	line int
+	// lineBeg is set if the token is the first of its line
+	lineBeg bool
+}
+
+// Reader reads games in Portable Game Notation from an input stream.
+type Reader struct {
+	r     *bufio.Reader
+	line  int
+	games int
+
+	peeked *token
+	// lineBeg is set while only whitespace has been read since the last
+	// newline
+	lineBeg bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), line: 1, lineBeg: true}
+}
+
+// Next() reads the next game. It returns io.EOF when there are no more games.
+// A malformed game is reported with a *ParseError and skipped, Next() can be
+// called again to read the following games.
+func (r *Reader) Next() (*Game, error) {
+	tok, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tok.typ == tokEOF {
+		return nil, io.EOF
+	}
+
+	r.games++
+	g, err := r.readGame()
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			if serr := r.skipGame(); serr != nil {
+				return nil, serr
+			}
+		}
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (r *Reader) errorf(line int, format string, a ...any) error {
+	return &ParseError{Game: r.games, Line: line, Err: fmt.Errorf(format, a...)}
+}
+
+func (r *Reader) readGame() (*Game, error) {
+	g := NewGame()
+
+	// Tag pair section
+	for {
+		tok, err := r.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.typ != tokLBracket {
+			break
+		}
+		r.next() //nolint:errcheck // already peeked
+
+		name, err := r.expect(tokSymbol, "tag name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.expect(tokString, "tag value")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.expect(tokRBracket, "]"); err != nil {
+			return nil, err
+		}
+		g.Tags = append(g.Tags, Tag{Name: name.val, Value: value.val})
+	}
+
+	pos, err := g.StartPosition()
+	if err != nil {
+		tok, _ := r.peek()
+		return nil, r.errorf(tok.line, "%w", err)
+	}
+
+	// Movetext section
+	cur := g.Root
+	var variations []*Node
+	// A comment at the start of a variation precedes the first move
+	var comment string
+	varStart := false
+
+	for {
+		tok, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok.typ {
+		case tokEOF, tokLBracket:
+			if len(variations) != 0 {
+				// The token starts the next game, it is left for skipGame()
+				r.peeked = &tok
+				return nil, r.errorf(tok.line, "unterminated variation")
+			}
+			// Game without a result token, leave the token to the next game
+			r.peeked = &tok
+			if g.Tag("Result") != "" {
+				g.Result = g.Tag("Result")
+			}
+			g.Moves = g.Root.MainLine()
+			return g, nil
+		case tokPeriod:
+		case tokComment:
+			if varStart {
+				comment = joinComments(comment, tok.val)
+			} else {
+				cur.Comment = joinComments(cur.Comment, tok.val)
+			}
+		case tokNAG:
+			n, err := strconv.Atoi(tok.val)
+			if err != nil || cur == g.Root {
+				return nil, r.errorf(tok.line, "invalid NAG $%v", tok.val)
+			}
+			cur.NAGs = append(cur.NAGs, n)
+		case tokLParen:
+			if cur == g.Root {
+				return nil, r.errorf(tok.line, "variation before the first move")
+			}
+			variations = append(variations, cur)
+			pos.UndoMove(cur.Move)
+			cur = cur.Parent
+			varStart = true
+		case tokRParen:
+			if len(variations) == 0 {
+				return nil, r.errorf(tok.line, "unexpected )")
+			}
+			last := variations[len(variations)-1]
+			variations = variations[:len(variations)-1]
+			for ; cur != last.Parent; cur = cur.Parent {
+				pos.UndoMove(cur.Move)
+			}
+			pos.DoMove(last.Move)
+			cur = last
+			comment = ""
+			varStart = false
+		case tokSymbol:
+			switch {
+			case isResult(tok.val):
+				if len(variations) != 0 {
+					// The token ends the game, it is left for skipGame()
+					r.peeked = &tok
+					return nil, r.errorf(tok.line, "game termination %v inside a variation", tok.val)
+				}
+				g.Result = tok.val
+				g.Moves = g.Root.MainLine()
+				return g, nil
+			case isMoveNumber(tok.val):
+			case isAnnotation(tok.val):
+				if cur == g.Root {
+					return nil, r.errorf(tok.line, "annotation %v before the first move", tok.val)
+				}
+				cur.NAGs = append(cur.NAGs, annotationToNAG[tok.val])
+			default:
+				san, nag := splitAnnotation(tok.val)
+				m, err := pos.ParseSAN(san)
+				if err != nil {
+					return nil, r.errorf(tok.line, "%w", err)
+				}
+				pos.DoMove(m)
+				cur = cur.AddChild(m)
+				if nag != 0 {
+					cur.NAGs = append(cur.NAGs, nag)
+				}
+				cur.StartingComment = comment
+				comment = ""
+				varStart = false
+			}
+		default:
+			return nil, r.errorf(tok.line, "unexpected token %q", tok.val)
+		}
+	}
+}
+
+// skipGame() skips the rest of a malformed game, up to its result or to the
+// tag pair section of the next game.
+func (r *Reader) skipGame() error {
+	for {
+		tok, err := r.next()
+		if err != nil {
+			var perr *ParseError
+			if errors.As(err, &perr) {
+				continue
+			}
+			return err
+		}
+
+		switch {
+		case tok.typ == tokEOF:
+			return nil
+		case tok.typ == tokLBracket && tok.lineBeg:
+			r.peeked = &tok
+			return nil
+		case tok.typ == tokSymbol && isResult(tok.val):
+			return nil
+		}
+	}
+}
+
+// expect() reads a token of type typ. An unexpected token is left unread, it
+// may start the next game.
+func (r *Reader) expect(typ tokenType, what string) (token, error) {
+	tok, err := r.next()
+	if err != nil {
+		return tok, err
+	}
+	if tok.typ != typ {
+		r.peeked = &tok
+		return tok, r.errorf(tok.line, "expected %v, got %q", what, tok.val)
+	}
+	return tok, nil
+}
+
+func (r *Reader) peek() (token, error) {
+	if r.peeked == nil {
+		tok, err := r.readToken()
+		if err != nil {
+			return tok, err
+		}
+		r.peeked = &tok
+	}
+	return *r.peeked, nil
+}
+
+func (r *Reader) next() (token, error) {
+	if r.peeked != nil {
+		tok := *r.peeked
+		r.peeked = nil
+		return tok, nil
+	}
+	return r.readToken()
+}
+
+func (r *Reader) readRune() (rune, error) {
+	c, _, err := r.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if c == '\n' {
+		r.line++
+	}
+	return c, nil
+}
+
+func (r *Reader) unreadRune(c rune) {
+	r.r.UnreadRune() //nolint:errcheck // always follows a successful ReadRune
+	if c == '\n' {
+		r.line--
+	}
+}
+
+// readToken() reads the next token from the stream, it returns a token of
+// type tokEOF at the end of the input.
+func (r *Reader) readToken() (token, error) {
+	for {
+		c, err := r.readRune()
+		if err == io.EOF {
+			return token{typ: tokEOF, line: r.line}, nil
+		}
+		if err != nil {
+			return token{}, err
+		}
+
+		lineBeg := r.lineBeg
+		if c == '\n' {
+			r.lineBeg = true
+		} else if !unicode.IsSpace(c) {
+			r.lineBeg = false
+		}
+		line := r.line
+
+		switch {
+		case unicode.IsSpace(c):
+		case c == '%' && lineBeg:
+			// Escape mechanism, the rest of the line is ignored
+			if err := r.skipLine(); err != nil {
+				return token{typ: tokEOF, line: r.line}, nil
+			}
+			r.lineBeg = true
+		case c == ';':
+			var sb strings.Builder
+			for {
+				c, err := r.readRune()
+				if err != nil || c == '\n' {
+					break
+				}
+				sb.WriteRune(c)
+			}
+			r.lineBeg = true
+			return token{typ: tokComment, val: strings.TrimSpace(sb.String()), line: line}, nil
+		case c == '{':
+			var sb strings.Builder
+			for {
+				c, err := r.readRune()
+				if err != nil {
+					return token{}, &ParseError{Game: r.games, Line: line, Err: errors.New("unterminated comment")}
+				}
+				if c == '}' {
+					break
+				}
+				sb.WriteRune(c)
+			}
+			return token{typ: tokComment, val: strings.Join(strings.Fields(sb.String()), " "), line: line}, nil
+		case c == '"':
+			var sb strings.Builder
+			for {
+				c, err := r.readRune()
+				if err != nil || c == '\n' {
+					return token{}, &ParseError{Game: r.games, Line: line, Err: errors.New("unterminated string")}
+				}
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					if c, err = r.readRune(); err != nil {
+						return token{}, &ParseError{Game: r.games, Line: line, Err: errors.New("unterminated string")}
+					}
+				}
+				sb.WriteRune(c)
+			}
+			return token{typ: tokString, val: sb.String(), line: line}, nil
+		case c == '$':
+			val := r.readWhile(unicode.IsDigit)
+			return token{typ: tokNAG, val: val, line: line}, nil
+		case c == '.':
+			return token{typ: tokPeriod, val: ".", line: line}, nil
+		case c == '[':
+			return token{typ: tokLBracket, val: "[", line: line, lineBeg: lineBeg}, nil
+		case c == ']':
+			return token{typ: tokRBracket, val: "]", line: line}, nil
+		case c == '(':
+			return token{typ: tokLParen, val: "(", line: line}, nil
+		case c == ')':
+			return token{typ: tokRParen, val: ")", line: line}, nil
+		case c == '*':
+			return token{typ: tokSymbol, val: "*", line: line}, nil
+		case isSymbolRune(c):
+			val := string(c) + r.readWhile(isSymbolRune)
+			return token{typ: tokSymbol, val: val, line: line}, nil
+		default:
+			return token{}, &ParseError{Game: r.games, Line: line, Err: fmt.Errorf("unexpected character %q", c)}
+		}
+	}
+}
+
+func (r *Reader) readWhile(f func(rune) bool) string {
+	var sb strings.Builder
+	for {
+		c, err := r.readRune()
+		if err != nil {
+			break
+		}
+		if !f(c) {
+			r.unreadRune(c)
+			break
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (r *Reader) skipLine() error {
+	for {
+		c, err := r.readRune()
+		if err != nil {
+			return err
+		}
+		if c == '\n' {
+			return nil
+		}
+	}
+}
+
+func isSymbolRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_+#=:-/!?", c)
+}
+
+func isResult(s string) bool {
+	return s == "1-0" || s == "0-1" || s == "1/2-1/2" || s == "*"
+}
+
+func isMoveNumber(s string) bool {
+	for _, c := range s {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+var annotationToNAG = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+func isAnnotation(s string) bool {
+	_, ok := annotationToNAG[s]
+	return ok
+}
+
+// splitAnnotation() splits a move with a traditional suffix annotation (e4!?)
+// into the move and the equivalent NAG.
+func splitAnnotation(s string) (string, int) {
+	san := strings.TrimRight(s, "!?")
+	return san, annotationToNAG[s[len(san):]]
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
@@ -0,0 +1,123 @@
+package pgn
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const validGame = `[Event "Valid"]
+[Site "?"]
+[Result "1-0"]
+
+1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0
+`
+
+func TestReaderGame(t *testing.T) {
+	pgn := `[Event "Annotated"]
+[White "A"]
+[Black "B"]
+[Result "1/2-1/2"]
+
+{Opening} 1. e4 $1 e5!? 2. Nf3 (2. f4 {King's Gambit} exf4 (2... d5)) 2... Nc6
+; rest of line comment
+3. Bb5 a6 1/2-1/2
+`
+	r := NewReader(strings.NewReader(pgn))
+	g, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Tag("Event") != "Annotated" || g.Tag("White") != "A" || g.Tag("Black") != "B" {
+		t.Errorf("tags: %v", g.Tags)
+	}
+	if g.Result != "1/2-1/2" {
+		t.Errorf("result: got %v, want 1/2-1/2", g.Result)
+	}
+	if g.Root.Comment != "Opening" {
+		t.Errorf("comment before the first move: got %q", g.Root.Comment)
+	}
+	if len(g.Moves) != 6 {
+		t.Errorf("got %d moves, want 6", len(g.Moves))
+	}
+
+	e4 := g.Root.Children[0]
+	if len(e4.NAGs) != 1 || e4.NAGs[0] != 1 {
+		t.Errorf("e4 NAGs: got %v, want [1]", e4.NAGs)
+	}
+	e5 := e4.Children[0]
+	if len(e5.NAGs) != 1 || e5.NAGs[0] != 5 {
+		t.Errorf("e5!? NAGs: got %v, want [5]", e5.NAGs)
+	}
+	if len(e5.Children) != 2 {
+		t.Fatalf("got %d moves after e5, want 2", len(e5.Children))
+	}
+	f4 := e5.Children[1]
+	if f4.Comment != "King's Gambit" || len(f4.Children) != 2 {
+		t.Errorf("2. f4 variation: comment %q, %d replies", f4.Comment, len(f4.Children))
+	}
+	if nc6 := e5.Children[0].Children[0]; nc6.Comment != "rest of line comment" {
+		t.Errorf("Nc6 comment: got %q", nc6.Comment)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+// TestReaderSkip checks that a malformed game is reported and skipped, and
+// that the following game is read whole.
+func TestReaderSkip(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+	}{
+		{"illegal move", "[Event \"Bad\"]\n\n1. e4 e5 2. Ke3 Nc6 1-0\n"},
+		{"result in a variation", "[Event \"Bad\"]\n\n1. e4 (1. d4 1-0\n"},
+		{"unterminated variation", "[Event \"Bad\"]\n\n1. e4 (1. d4 d5\n"},
+		{"unterminated tag", "[Event \"Bad\"\n"},
+		{"tag without value", "[Event]\n"},
+		{"unexpected )", "[Event \"Bad\"]\n\n1. e4 ) e5 *\n"},
+		{"invalid FEN", "[Event \"Bad\"]\n[SetUp \"1\"]\n[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n1. e4 *\n"},
+		{"annotation before the first move", "[Event \"Bad\"]\n\n!? 1. e4 *\n"},
+	}
+
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.pgn + "\n" + validGame))
+
+		_, err := r.Next()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: got %v, want a *ParseError", tt.name, err)
+			continue
+		}
+		if perr.Game != 1 {
+			t.Errorf("%s: error in game %d, want 1", tt.name, perr.Game)
+		}
+
+		g, err := r.Next()
+		if err != nil {
+			t.Errorf("%s: the next game: %v", tt.name, err)
+			continue
+		}
+		if g.Tag("Event") != "Valid" || g.Tag("Site") != "?" || len(g.Moves) != 7 || g.Result != "1-0" {
+			t.Errorf("%s: the next game has tags %v, %d moves and result %v", tt.name, g.Tags, len(g.Moves), g.Result)
+		}
+
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: got %v, want io.EOF", tt.name, err)
+		}
+	}
+
+	// The result ends the malformed game even inside a variation, the next
+	// game may have no tags
+	r := NewReader(strings.NewReader("1. e4 (1. d4 1-0\n\n1. d4 d5 *\n"))
+	if _, err := r.Next(); err == nil {
+		t.Fatal("result in a variation: expected an error")
+	}
+	if g, err := r.Next(); err != nil || len(g.Moves) != 2 {
+		t.Errorf("result in a variation: the next game: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package pgn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/engine"
+)
+
+// DefaultLineWidth is the maximum length of the movetext lines, as
+// recommended by the PGN standard.
+const DefaultLineWidth = 80
+
+// Seven Tag Roster, the tags that every game must have, in this order.
+var sevenTagRoster = []Tag{
+	{"Event", "?"},
+	{"Site", "?"},
+	{"Date", "????.??.??"},
+	{"Round", "?"},
+	{"White", "?"},
+	{"Black", "?"},
+	{"Result", "*"},
+}
+
+// Writer writes games in Portable Game Notation (export format) to an
+// output stream.
+type Writer struct {
+	w io.Writer
+	// buf holds the game being written, so that a game that cannot be
+	// written leaves nothing in the output
+	buf       bytes.Buffer
+	LineWidth int
+
+	line   int    // length of the current movetext line
+	prefix string // glued to the next token, the start of a variation
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, LineWidth: DefaultLineWidth}
+}
+
+// NewGameFromMoves() creates a game playing the moves from the start
+// position, adding the SetUp and FEN tags if start is not the standard
+// starting position.
+func NewGameFromMoves(start *engine.Position, moves []engine.Move) *Game {
+	g := NewGame()
+	if fen := start.Fen(); fen != engine.StartFEN {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+
+	n := g.Root
+	for _, m := range moves {
+		n = n.AddChild(m)
+	}
+	g.Moves = moves
+
+	return g
+}
+
+// Write() writes g, followed by an empty line. The game is written with a
+// single write to the output stream, or not at all on error.
+func (w *Writer) Write(g *Game) error {
+	pos, err := g.StartPosition()
+	if err != nil {
+		return fmt.Errorf("pgn: %w", err)
+	}
+
+	w.buf.Reset()
+	w.prefix = ""
+
+	for _, t := range sevenTagRoster {
+		value := g.Tag(t.Name)
+		if t.Name == "Result" {
+			value = g.Result
+		}
+		if value == "" {
+			value = t.Value
+		}
+		w.writeTag(t.Name, value)
+	}
+	for _, t := range g.Tags {
+		if !isSevenTagRoster(t.Name) {
+			w.writeTag(t.Name, t.Value)
+		}
+	}
+	w.buf.WriteString("\n")
+
+	w.line = 0
+	if g.Root.Comment != "" {
+		w.writeComment(g.Root.Comment)
+	}
+	if err := w.writeLine(pos, g.Root, true); err != nil {
+		return err
+	}
+	w.writeToken(g.Result)
+	w.buf.WriteString("\n\n")
+
+	_, err = w.w.Write(w.buf.Bytes())
+	return err
+}
+
+func (w *Writer) writeTag(name, value string) {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+	fmt.Fprintf(&w.buf, "[%s \"%s\"]\n", name, value)
+}
+
+// writeLine() writes the moves following n. When a move number must be
+// written even for a black move, e.g. after a comment, force is set.
+func (w *Writer) writeLine(pos *engine.Position, n *Node, force bool) error {
+	var played []engine.Move
+	defer func() {
+		for i := len(played) - 1; i >= 0; i-- {
+			pos.UndoMove(played[i])
+		}
+	}()
+
+	for len(n.Children) != 0 {
+		main := n.Children[0]
+		if err := w.writeMove(pos, main, force); err != nil {
+			return err
+		}
+		force = main.Comment != "" || main.Eval != nil || main.Clock != nil
+
+		for _, v := range n.Children[1:] {
+			w.prefix = "("
+			if err := w.writeMove(pos, v, true); err != nil {
+				return err
+			}
+			pos.DoMove(v.Move)
+			err := w.writeLine(pos, v, v.Comment != "" || v.Eval != nil || v.Clock != nil)
+			pos.UndoMove(v.Move)
+			if err != nil {
+				return err
+			}
+			w.closeVariation()
+			force = true
+		}
+
+		pos.DoMove(main.Move)
+		played = append(played, main.Move)
+		n = main
+	}
+
+	return nil
+}
+
+func (w *Writer) writeMove(pos *engine.Position, n *Node, force bool) error {
+	if n.StartingComment != "" {
+		w.writeComment(n.StartingComment)
+		force = true
+	}
+
+	var ml engine.MoveList
+	pos.Generate(engine.Legal, &ml)
+	if !ml.Contains(n.Move) {
+		return fmt.Errorf("pgn: illegal move %v in position %v", n.Move, pos.Fen())
+	}
+
+	// The move number is kept on the same line as the move
+	san := pos.MoveToSAN(n.Move)
+	number := strconv.Itoa(pos.GamePly()/2 + 1)
+	if pos.SideToMove() == engine.White {
+		san = number + ". " + san
+	} else if force {
+		san = number + "... " + san
+	}
+	w.writeToken(san)
+	for _, nag := range n.NAGs {
+		w.writeToken("$" + strconv.Itoa(nag))
+	}
+
+	var annotations []string
+	if n.Eval != nil {
+		annotations = append(annotations, "[%eval "+n.Eval.String()+"]")
+	}
+	if n.Clock != nil {
+		annotations = append(annotations, "[%clk "+formatClock(*n.Clock)+"]")
+	}
+	if len(annotations) != 0 || n.Comment != "" {
+		w.writeComment(n.Comment, annotations...)
+	}
+
+	return nil
+}
+
+// writeComment() writes a brace comment, preceded by the annotations. The
+// comment is split in words so it can be wrapped like the rest of the
+// movetext, while every annotation is kept on a single line.
+func (w *Writer) writeComment(comment string, annotations ...string) {
+	words := append(annotations, strings.Fields(strings.ReplaceAll(comment, "}", ""))...)
+	if len(words) == 0 {
+		w.writeToken("{}")
+		return
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	for _, word := range words {
+		w.writeToken(word)
+	}
+}
+
+func (w *Writer) closeVariation() {
+	if w.line+1 > w.LineWidth {
+		w.buf.WriteString("\n")
+		w.line = 0
+	}
+	w.buf.WriteString(")")
+	w.line++
+}
+
+func (w *Writer) writeToken(tok string) {
+	tok = w.prefix + tok
+	w.prefix = ""
+
+	switch {
+	case w.line == 0:
+	case w.line+1+len(tok) > w.LineWidth:
+		w.buf.WriteString("\n")
+		w.line = 0
+	default:
+		w.buf.WriteString(" ")
+		w.line++
+	}
+	w.buf.WriteString(tok)
+	w.line += len(tok)
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, t := range sevenTagRoster {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatClock() formats a clock time as h:mm:ss, as used by [%clk].
+func formatClock(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
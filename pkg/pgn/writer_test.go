@@ -0,0 +1,158 @@
+package pgn
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FotiadisM/spencer/pkg/engine"
+)
+
+// gameFromSAN() returns the game playing the SAN moves from the starting
+// position.
+func gameFromSAN(t *testing.T, moves string) *Game {
+	t.Helper()
+	pos, err := engine.NewPosition(engine.StartFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ml []engine.Move
+	for _, san := range strings.Fields(moves) {
+		m, err := pos.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos.DoMove(m)
+		ml = append(ml, m)
+	}
+	start, _ := engine.NewPosition(engine.StartFEN)
+	return NewGameFromMoves(start, ml)
+}
+
+func TestWriterSevenTagRoster(t *testing.T) {
+	g := gameFromSAN(t, "e4 e5")
+	g.SetTag("Annotator", "Spencer")
+	g.SetTag("White", "A")
+	g.SetTag("Event", "Test")
+	g.Result = "1/2-1/2"
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(g); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[Event "Test"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "A"]
+[Black "?"]
+[Result "1/2-1/2"]
+[Annotator "Spencer"]
+
+1. e4 e5 1/2-1/2
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriterAnnotations(t *testing.T) {
+	g := gameFromSAN(t, "e4 e5 Nf3")
+	e4 := g.Root.Children[0]
+	e4.Eval = &Eval{CP: 35}
+	clock := 90*time.Minute + 5*time.Second
+	e4.Clock = &clock
+	e4.Comment = "best by test"
+	e5 := e4.Children[0]
+	e5.Eval = &Eval{Mate: -3}
+	e5.NAGs = []int{2}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(g); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "1. e4 {[%eval 0.35] [%clk 1:30:05] best by test} 1... e5 $2 {[%eval #-3]} 2. Nf3 *"
+	movetext := strings.SplitN(buf.String(), "\n\n", 2)[1]
+	if got := strings.Join(strings.Fields(movetext), " "); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriterLineWrapping(t *testing.T) {
+	g := gameFromSAN(t, "e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7 Re1 b5 Bb3 d6 c3 O-O h3 Nb8 d4 Nbd7")
+	g.Root.Children[0].Comment = "a long comment that must be wrapped like the moves"
+
+	for _, width := range []int{20, 40, DefaultLineWidth} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.LineWidth = width
+		if err := w.Write(g); err != nil {
+			t.Fatal(err)
+		}
+
+		movetext := strings.SplitN(buf.String(), "\n\n", 2)[1]
+		var words []string
+		for _, line := range strings.Split(strings.TrimSpace(movetext), "\n") {
+			if len(line) > width {
+				t.Errorf("width %d: line %q is too long", width, line)
+			}
+			words = append(words, strings.Fields(line)...)
+		}
+
+		// Wrapping only replaces spaces with newlines
+		want := "1. e4 {a long comment that must be wrapped like the moves} 1... e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 " +
+			"5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7 *"
+		if got := strings.Join(words, " "); got != want {
+			t.Errorf("width %d: got\n%s\nwant\n%s", width, got, want)
+		}
+	}
+}
+
+type failingWriter struct {
+	n int // the number of writes that succeed
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestWriterError(t *testing.T) {
+	// A game with an illegal move writes nothing, the next game is written
+	// alone
+	bad := gameFromSAN(t, "e4 e5")
+	bad.Root.Children[0].Children[0].AddChild(bad.Root.Children[0].Move)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Write(bad); err == nil {
+		t.Fatal("expected an error for an illegal move")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("the game with an illegal move wrote %q", buf.String())
+	}
+	if err := w.Write(gameFromSAN(t, "d4")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Contains(got, "e4") || !strings.HasSuffix(got, "1. d4 *\n\n") {
+		t.Errorf("got\n%s", got)
+	}
+
+	// Every game is written at once, so a failed write leaves no partial
+	// game in the output
+	fw := &failingWriter{n: 1}
+	w = NewWriter(fw)
+	if err := w.Write(gameFromSAN(t, "e4")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(gameFromSAN(t, "d4")); err == nil {
+		t.Error("expected the write error")
+	}
+}
@@ -10,6 +10,21 @@ const (
 	String
 )
 
+func (t UCIOptionType) String() string {
+	switch t {
+	case Check:
+		return "check"
+	case Spin:
+		return "spin"
+	case Combo:
+		return "combo"
+	case Button:
+		return "button"
+	default:
+		return "string"
+	}
+}
+
 type EngineOption interface {
 	Name() string
 	Type() UCIOptionType
@@ -26,7 +41,7 @@ type UCIOptionSpin interface {
 	Default() int
 	Min() int
 	Max() int
-	St(int)
+	Set(int)
 }
 
 type UCIOptionCombo interface {
@@ -71,8 +86,10 @@ type EngineSearchLimits struct {
 type Engine interface {
 	SetDebug(b bool, out chan string)
 	NewGame(out chan string)
-	SetPosition(fen string, out chan string)
-	ApplyMove(mv string, out chan string)
+	SetPosition(fen string, out chan string) error
+	ApplyMove(mv string, out chan string) error
 	Search(esl EngineSearchLimits, out chan string)
 	Stop() (bm string, po string)
+	PonderHit(out chan string)
+	Perft(depth int, out chan string)
 }
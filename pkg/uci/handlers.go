@@ -2,6 +2,7 @@ package uci
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -56,8 +57,62 @@ func isReadyHandler(e Engine, out chan string) {
 	out <- "readyok\n"
 }
 
+// setOptionHandler() handles "setoption name <id> [value <x>]", where both
+// the name and the value can contain spaces.
 func setOptionHandler(e Engine, opts []EngineOption, str []string, out chan string) {
-	// TODO: implement
+	if len(str) < 3 || str[1] != "name" {
+		out <- "info string error invalid command\n"
+		return
+	}
+
+	n := 2
+	for n < len(str) && str[n] != "value" {
+		n++
+	}
+	name := strings.Join(str[2:n], " ")
+	value := ""
+	if n < len(str) {
+		value = strings.Join(str[n+1:], " ")
+	}
+
+	var opt EngineOption
+	for _, o := range opts {
+		if strings.EqualFold(o.Name(), name) {
+			opt = o
+			break
+		}
+	}
+	if opt == nil {
+		out <- fmt.Sprintf("info string error unknown option %v\n", name)
+		return
+	}
+
+	switch opt.Type() {
+	case Check:
+		o := opt.(UCIOptionCheck)
+		switch value {
+		case "true":
+			o.Set(true)
+		case "false":
+			o.Set(false)
+		default:
+			out <- fmt.Sprintf("info string error invalid value %v for option %v\n", value, o.Name())
+		}
+	case Spin:
+		o := opt.(UCIOptionSpin)
+		v, err := strconv.Atoi(value)
+		if err != nil || v < o.Min() || v > o.Max() {
+			out <- fmt.Sprintf("info string error invalid value %v for option %v\n", value, o.Name())
+			return
+		}
+		o.Set(v)
+	case Combo:
+		opt.(UCIOptionCombo).Set(value)
+	case Button:
+		opt.(UCIOptionButton).Set()
+	case String:
+		opt.(UCIOptionString).Set(value)
+	}
 }
 
 func registerHandler(e Engine, str []string, out chan string) {
@@ -75,9 +130,10 @@ func positionHandler(e Engine, str []string, out chan string) {
 	}
 	str = str[1:]
 
+	var err error
 	switch str[0] {
 	case "startpos":
-		e.SetPosition(startFen, out)
+		err = e.SetPosition(startFen, out)
 		str = str[1:]
 	case "fen":
 		if len(str) == 1 {
@@ -85,16 +141,24 @@ func positionHandler(e Engine, str []string, out chan string) {
 			return
 		}
 		str = str[1:]
-		if len(str) < 6 {
+		n := 0
+		for n < len(str) && str[n] != "moves" {
+			n++
+		}
+		if n == 0 {
 			out <- "info string error invalid command\n"
 			return
 		}
-		e.SetPosition(strings.Join(str[:6], " "), out)
-		str = str[6:]
+		err = e.SetPosition(strings.Join(str[:n], " "), out)
+		str = str[n:]
 	default:
 		out <- "info string error invalid command\n"
 		return
 	}
+	if err != nil {
+		out <- fmt.Sprintf("info string error %v\n", err)
+		return
+	}
 
 	if len(str) != 0 {
 		if str[0] != "moves" {
@@ -103,19 +167,117 @@ func positionHandler(e Engine, str []string, out chan string) {
 		}
 		str = str[1:]
 		for _, m := range str {
-			e.ApplyMove(m, out)
+			if err := e.ApplyMove(m, out); err != nil {
+				out <- fmt.Sprintf("info string error %v\n", err)
+				return
+			}
 		}
 	}
 }
 
 func goHandler(e Engine, str []string, out chan string) {
-	// TODO: parse str
-	esl := EngineSearchLimits{}
-	go e.Search(esl, out)
+	if len(str) > 1 && str[1] == "perft" {
+		perftHandler(e, str, out)
+		return
+	}
+
+	esl, err := parseGo(str[1:])
+	if err != nil {
+		out <- fmt.Sprintf("info string error %v\n", err)
+		return
+	}
+	e.Search(esl, out)
+}
+
+// parseGo() parses the parameters of the go command. searchmoves takes all
+// the following moves, up to the next parameter.
+func parseGo(str []string) (EngineSearchLimits, error) {
+	var esl EngineSearchLimits
+
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case "searchmoves":
+			for i+1 < len(str) && !isGoParameter(str[i+1]) {
+				i++
+				esl.SearchMoves = append(esl.SearchMoves, str[i])
+			}
+			if len(esl.SearchMoves) == 0 {
+				return esl, fmt.Errorf("missing moves for searchmoves")
+			}
+		case "ponder":
+			esl.Ponder = true
+		case "infinite":
+			esl.Infinite = true
+		case "wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime":
+			if i+1 == len(str) {
+				return esl, fmt.Errorf("missing value for %v", str[i])
+			}
+			v, err := strconv.Atoi(str[i+1])
+			// The clock can be negative when the GUI is late
+			if err != nil || (v < 0 && str[i] != "wtime" && str[i] != "btime") {
+				return esl, fmt.Errorf("invalid value %v for %v", str[i+1], str[i])
+			}
+			*goIntParameter(&esl, str[i]) = v
+			i++
+		default:
+			return esl, fmt.Errorf("unknown parameter %v", str[i])
+		}
+	}
+
+	return esl, nil
+}
+
+func isGoParameter(s string) bool {
+	switch s {
+	case "searchmoves", "ponder", "infinite", "wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime":
+		return true
+	}
+	return false
+}
+
+func goIntParameter(esl *EngineSearchLimits, name string) *int {
+	switch name {
+	case "wtime":
+		return &esl.WTime
+	case "btime":
+		return &esl.BTime
+	case "winc":
+		return &esl.WInc
+	case "binc":
+		return &esl.BInc
+	case "movestogo":
+		return &esl.MovesToGo
+	case "depth":
+		return &esl.Depth
+	case "nodes":
+		return &esl.Nodes
+	case "mate":
+		return &esl.Mate
+	default:
+		return &esl.MoveTime
+	}
+}
+
+// perftHandler() handles the non standard "go perft <depth>" command.
+func perftHandler(e Engine, str []string, out chan string) {
+	if len(str) != 3 {
+		out <- "info string error invalid command\n"
+		return
+	}
+	depth, err := strconv.Atoi(str[2])
+	if err != nil || depth < 1 {
+		out <- fmt.Sprintf("info string error invalid depth %v\n", str[2])
+		return
+	}
+	e.Perft(depth, out)
 }
 
 func stopHandler(e Engine, out chan string) {
 	bm, po := e.Stop()
+	if bm == "" {
+		// No search is running, or it already sent its best move
+		return
+	}
 	if po == "" {
 		out <- fmt.Sprintf("bestmove %v\n", bm)
 		return
@@ -124,5 +286,5 @@ func stopHandler(e Engine, out chan string) {
 }
 
 func ponderHitHandler(e Engine, out chan string) {
-	// TODO: implement
+	e.PonderHit(out)
 }
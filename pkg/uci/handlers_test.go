@@ -0,0 +1,61 @@
+package uci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGo(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want EngineSearchLimits
+	}{
+		{"", EngineSearchLimits{}},
+		{"infinite", EngineSearchLimits{Infinite: true}},
+		{"depth 12", EngineSearchLimits{Depth: 12}},
+		{"nodes 100000 movetime 500", EngineSearchLimits{Nodes: 100000, MoveTime: 500}},
+		{"mate 3", EngineSearchLimits{Mate: 3}},
+		{"wtime 60000 btime 55000 winc 1000 binc 900", EngineSearchLimits{WTime: 60000, BTime: 55000, WInc: 1000, BInc: 900}},
+		{"wtime 1000 btime 2000 movestogo 20", EngineSearchLimits{WTime: 1000, BTime: 2000, MovesToGo: 20}},
+		{"ponder wtime -20 btime 3000", EngineSearchLimits{Ponder: true, WTime: -20, BTime: 3000}},
+		{"searchmoves e2e4 d2d4", EngineSearchLimits{SearchMoves: []string{"e2e4", "d2d4"}}},
+		{"searchmoves e2e4 g1f3 depth 5 infinite", EngineSearchLimits{SearchMoves: []string{"e2e4", "g1f3"}, Depth: 5, Infinite: true}},
+		{"depth 5 searchmoves e7e8q", EngineSearchLimits{Depth: 5, SearchMoves: []string{"e7e8q"}}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGo(strings.Fields(tt.cmd))
+		if err != nil {
+			t.Errorf("go %v: %v", tt.cmd, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("go %v: got %+v, want %+v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestParseGoInvalid(t *testing.T) {
+	tests := []string{
+		"depth",
+		"depth x",
+		"depth 1.5",
+		"depth -1",
+		"nodes 99999999999999999999",
+		"movetime -100",
+		"winc -10",
+		"movestogo x",
+		"wtime 1000 btime",
+		"searchmoves",
+		"searchmoves depth 5",
+		"perft 3",
+		"depth 5 extra",
+	}
+
+	for _, cmd := range tests {
+		if esl, err := parseGo(strings.Fields(cmd)); err == nil {
+			t.Errorf("go %v: got %+v, want an error", cmd, esl)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package uci
+
+// The option types below implement the option interfaces, calling a function
+// when the GUI changes their value.
+
+type checkOption struct {
+	name string
+	def  bool
+	set  func(bool)
+}
+
+func NewCheckOption(name string, def bool, set func(bool)) UCIOptionCheck {
+	return &checkOption{name: name, def: def, set: set}
+}
+
+func (o *checkOption) Name() string        { return o.name }
+func (o *checkOption) Type() UCIOptionType { return Check }
+func (o *checkOption) Default() bool       { return o.def }
+func (o *checkOption) Set(b bool)          { o.set(b) }
+
+type spinOption struct {
+	name          string
+	def, min, max int
+	set           func(int)
+}
+
+func NewSpinOption(name string, def, min, max int, set func(int)) UCIOptionSpin {
+	return &spinOption{name: name, def: def, min: min, max: max, set: set}
+}
+
+func (o *spinOption) Name() string        { return o.name }
+func (o *spinOption) Type() UCIOptionType { return Spin }
+func (o *spinOption) Default() int        { return o.def }
+func (o *spinOption) Min() int            { return o.min }
+func (o *spinOption) Max() int            { return o.max }
+func (o *spinOption) Set(n int)           { o.set(n) }
+
+type buttonOption struct {
+	name string
+	set  func()
+}
+
+func NewButtonOption(name string, set func()) UCIOptionButton {
+	return &buttonOption{name: name, set: set}
+}
+
+func (o *buttonOption) Name() string        { return o.name }
+func (o *buttonOption) Type() UCIOptionType { return Button }
+func (o *buttonOption) Set()                { o.set() }
+
+type stringOption struct {
+	name string
+	def  string
+	set  func(string)
+}
+
+func NewStringOption(name string, def string, set func(string)) UCIOptionString {
+	return &stringOption{name: name, def: def, set: set}
+}
+
+func (o *stringOption) Name() string        { return o.name }
+func (o *stringOption) Type() UCIOptionType { return String }
+func (o *stringOption) Default() string     { return o.def }
+func (o *stringOption) Set(s string)        { o.set(s) }